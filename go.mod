@@ -1,3 +1,3 @@
 module github.com/omnikron13/splicearrays
 
-go 1.22.1
+go 1.23
@@ -0,0 +1,147 @@
+package tree
+
+import (
+	"fmt"
+	"iter"
+)
+
+// BitSlab is a Slab of bools packed into 64 bit words, using a single bit per item rather than a byte.
+// Combined with a TreeSlab it can be used as a splice-able bitmap.
+type BitSlab struct {
+	words []uint64
+	n     uint32
+}
+
+func (s *BitSlab) Add(items ...bool) (start uint32, length uint32) {
+	start = s.n
+	length = checkedAdd(0, uint64(len(items)))
+	checkedAdd(start, uint64(length))
+	for _, b := range items {
+		if s.n%64 == 0 {
+			s.words = append(s.words, 0)
+		}
+		s.n++
+		s.Set(s.n-1, b)
+	}
+	return
+}
+
+func (s *BitSlab) Get(index uint32) bool {
+	return s.words[index/64]&(1<<(index%64)) != 0
+}
+
+// GetRef returns a reference to a copy of the item at the given index, as individual bits have no address.
+// Writing through the reference does not change the slab, use Set instead.
+func (s *BitSlab) GetRef(index uint32) *bool {
+	b := s.Get(index)
+	return &b
+}
+
+func (s *BitSlab) Set(index uint32, item bool) {
+	if item {
+		s.words[index/64] |= 1 << (index % 64)
+	} else {
+		s.words[index/64] &^= 1 << (index % 64)
+	}
+}
+
+func (s *BitSlab) Len() uint32 {
+	return s.n
+}
+
+func (s *BitSlab) SliceIter(start uint32, length uint32) iter.Seq[bool] {
+	return getIter(s, start, length)
+}
+
+// SpanIter iterates over length items from the start index, yielding unpacked copies of them.
+func (s *BitSlab) SpanIter(start uint32, length uint32) iter.Seq[[]bool] {
+	return copySpans(s, start, length)
+}
+
+// PackedInteger is the constraint for the element types a PackedSlab can store.
+type PackedInteger interface {
+	~uint8 | ~uint16
+}
+
+// PackedSlab is a Slab of small unsigned integers, packed into 64 bit words using a fixed number of bits each.
+// Items may straddle two words, so no space is wasted for widths which don't divide 64.
+type PackedSlab[T PackedInteger] struct {
+	words []uint64
+	width uint32
+	n     uint32
+}
+
+// NewPackedSlab creates a new, empty, PackedSlab storing items using the given number of bits each.
+// Bits of added items above the width are discarded.
+// It returns an error if the width is zero, or wider than T.
+func NewPackedSlab[T PackedInteger](width uint32) (*PackedSlab[T], error) {
+	var zero T
+	if width == 0 || width > uint32(bitsOf[T]()) {
+		return nil, fmt.Errorf("width %d is invalid for %T", width, zero)
+	}
+	return &PackedSlab[T]{width: width}, nil
+}
+
+// bitsOf returns the number of bits in a PackedInteger type.
+func bitsOf[T PackedInteger]() (n int) {
+	for v := ^T(0); v != 0; v >>= 1 {
+		n++
+	}
+	return
+}
+
+func (s *PackedSlab[T]) Add(items ...T) (start uint32, length uint32) {
+	start = s.n
+	length = checkedAdd(0, uint64(len(items)))
+	checkedAdd(start, uint64(length))
+	words := (uint64(s.n+length)*uint64(s.width) + 63) / 64
+	for uint64(len(s.words)) < words {
+		s.words = append(s.words, 0)
+	}
+	for _, v := range items {
+		s.n++
+		s.Set(s.n-1, v)
+	}
+	return
+}
+
+func (s *PackedSlab[T]) Get(index uint32) T {
+	bit := uint64(index) * uint64(s.width)
+	w, shift := bit/64, bit%64
+	v := s.words[w] >> shift
+	if shift+uint64(s.width) > 64 {
+		v |= s.words[w+1] << (64 - shift)
+	}
+	return T(v & (1<<s.width - 1))
+}
+
+// GetRef returns a reference to a copy of the item at the given index, as packed items have no address.
+// Writing through the reference does not change the slab, use Set instead.
+func (s *PackedSlab[T]) GetRef(index uint32) *T {
+	v := s.Get(index)
+	return &v
+}
+
+func (s *PackedSlab[T]) Set(index uint32, item T) {
+	mask := uint64(1)<<s.width - 1
+	v := uint64(item) & mask
+	bit := uint64(index) * uint64(s.width)
+	w, shift := bit/64, bit%64
+	s.words[w] = s.words[w]&^(mask<<shift) | v<<shift
+	if shift+uint64(s.width) > 64 {
+		s.words[w+1] = s.words[w+1]&^(mask>>(64-shift)) | v>>(64-shift)
+	}
+}
+
+func (s *PackedSlab[T]) Len() uint32 {
+	return s.n
+}
+
+func (s *PackedSlab[T]) SliceIter(start uint32, length uint32) iter.Seq[T] {
+	return getIter(s, start, length)
+}
+
+// SpanIter iterates over length items from the start index, yielding unpacked copies of them.
+func (s *PackedSlab[T]) SpanIter(start uint32, length uint32) iter.Seq[[]T] {
+	return copySpans(s, start, length)
+}
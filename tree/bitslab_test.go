@@ -0,0 +1,76 @@
+package tree
+
+import "testing"
+
+func TestBitSlab(t *testing.T) {
+	s := &BitSlab{}
+	for i := 0; i < 200; i++ {
+		s.Add(i%3 == 0)
+	}
+	if s.Len() != 200 {
+		t.Error("Expected 200, got", s.Len())
+	}
+	for i := uint32(0); i < 200; i++ {
+		if s.Get(i) != (i%3 == 0) {
+			t.Error("Expected", i%3 == 0, "at", i)
+		}
+	}
+	s.Set(1, true)
+	s.Set(3, false)
+	if !s.Get(1) || s.Get(3) || !s.Get(6) {
+		t.Error("Set changed the wrong bits")
+	}
+	*s.GetRef(2) = true
+	if s.Get(2) {
+		t.Error("Expected GetRef to return a copy")
+	}
+}
+
+func TestBitSlab_Splice(t *testing.T) {
+	var bits Slab[bool] = &BitSlab{}
+	ts := NewTreeSlab()
+	root := ts.AddLeaf(bits.Add(false, false, false, false))
+	root = ts.insert(root, 2, ts.AddLeaf(bits.Add(true, true)))
+	expected := []bool{false, false, true, true, false, false}
+	i := 0
+	for n := range ts.IndexIter(root) {
+		if bits.Get(n) != expected[i] {
+			t.Error("Expected", expected[i], "at", i)
+		}
+		i++
+	}
+}
+
+func TestNewPackedSlab(t *testing.T) {
+	if _, err := NewPackedSlab[uint8](0); err == nil {
+		t.Error("Expected an error for width 0")
+	}
+	if _, err := NewPackedSlab[uint8](9); err == nil {
+		t.Error("Expected an error for width 9")
+	}
+	if _, err := NewPackedSlab[uint16](16); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPackedSlab(t *testing.T) {
+	for _, width := range []uint32{1, 3, 7, 12, 16} {
+		s, _ := NewPackedSlab[uint16](width)
+		mask := uint16(1<<width - 1)
+		expect := func(i uint32) uint16 {
+			return uint16(i*7919) & mask
+		}
+		for i := uint32(0); i < 300; i++ {
+			s.Add(uint16(i * 7919))
+		}
+		for i := uint32(0); i < 300; i++ {
+			if s.Get(i) != expect(i) {
+				t.Errorf("width %d: expected %d at %d, got %d", width, expect(i), i, s.Get(i))
+			}
+		}
+		s.Set(100, mask)
+		if s.Get(100) != mask || s.Get(99) != expect(99) || s.Get(101) != expect(101) {
+			t.Errorf("width %d: Set changed the wrong bits", width)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package tree
+
+import (
+	"errors"
+	"io"
+)
+
+// A ByteRope is a SpliceArray of bytes which implements the standard io interfaces for reading it, with an offset
+// used by Read and Seek, in the same way as a bytes.Reader.
+// Edits return a new ByteRope, sharing the data slab and TreeSlab of the original, with its offset at the start.
+// The zero ByteRope is empty and ready to use, like a zero bytes.Reader.
+type ByteRope struct {
+	sa  SpliceArray[byte]
+	off int64
+}
+
+// NewByteRope creates a new ByteRope holding a copy of the given bytes, with a new MinimalSlab and TreeSlab.
+func NewByteRope(b []byte) *ByteRope {
+	return &ByteRope{sa: NewSpliceArray(b...)}
+}
+
+// ByteRopeOf returns a ByteRope over the given SpliceArray.
+func ByteRopeOf(sa SpliceArray[byte]) *ByteRope {
+	return &ByteRope{sa: sa}
+}
+
+// SpliceArray returns the SpliceArray the ByteRope is over.
+func (r *ByteRope) SpliceArray() SpliceArray[byte] {
+	return r.sa
+}
+
+// Len returns the number of bytes in the ByteRope.
+func (r *ByteRope) Len() uint32 {
+	return r.sa.Len()
+}
+
+// Size returns the number of bytes in the ByteRope, as an int64 for use with offsets.
+func (r *ByteRope) Size() int64 {
+	return int64(r.sa.Len())
+}
+
+// Insert returns a new ByteRope with the given bytes inserted at the given position.
+// It panics if the position is past the end, as SpliceArray.Insert does.
+func (r *ByteRope) Insert(pos uint32, b []byte) *ByteRope {
+	return &ByteRope{sa: r.sa.Insert(pos, b...)}
+}
+
+// Remove returns a new ByteRope with length bytes removed from the given position.
+// It panics if the range extends past the end, as SpliceArray.Remove does.
+func (r *ByteRope) Remove(start, length uint32) *ByteRope {
+	return &ByteRope{sa: r.sa.Remove(start, length)}
+}
+
+// Bytes returns a copy of the bytes of the ByteRope.
+func (r *ByteRope) Bytes() []byte {
+	b := make([]byte, r.Size())
+	r.ReadAt(b, 0)
+	return b
+}
+
+// String returns the bytes of the ByteRope as a string.
+func (r *ByteRope) String() string {
+	return string(r.Bytes())
+}
+
+// MarshalText returns a copy of the bytes of the ByteRope.
+// It implements encoding.TextMarshaler.
+func (r *ByteRope) MarshalText() ([]byte, error) {
+	return r.Bytes(), nil
+}
+
+// UnmarshalText replaces the ByteRope with a new one holding a copy of the given text.
+// It implements encoding.TextUnmarshaler.
+func (r *ByteRope) UnmarshalText(text []byte) error {
+	*r = *NewByteRope(text)
+	return nil
+}
+
+// ReadAt reads len(p) bytes from the given offset, descending to the leaf holding the offset and copying out each
+// contiguous run from there. It implements io.ReaderAt.
+func (r *ByteRope) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	size := r.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+	length := uint32(min(int64(len(p)), size-off))
+	cs, contiguous := r.sa.data.(ContiguousSlab[byte])
+	for s, l := range r.sa.tree.RangeSpanIter(r.sa.root, uint32(off), length) {
+		if contiguous {
+			n += copy(p[n:], cs.Span(s, l))
+			continue
+		}
+		for span := range r.sa.data.SpanIter(s, l) {
+			n += copy(p[n:], span)
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read reads up to len(p) bytes from the offset, advancing it. It implements io.Reader.
+func (r *ByteRope) Read(p []byte) (n int, err error) {
+	if r.off >= r.Size() {
+		return 0, io.EOF
+	}
+	n, _ = r.ReadAt(p, r.off)
+	r.off += int64(n)
+	return n, nil
+}
+
+// WriteTo writes the bytes from the offset to the end to w, one leaf at a time, advancing the offset.
+// It implements io.WriterTo.
+func (r *ByteRope) WriteTo(w io.Writer) (int64, error) {
+	size := r.Size()
+	if r.off >= size {
+		return 0, nil
+	}
+	n, err := WriteSpans(w, r.sa.tree, r.sa.root, r.sa.data, uint32(r.off), uint32(size-r.off))
+	r.off += n
+	return n, err
+}
+
+// Seek sets the offset for the next Read or WriteTo. It implements io.Seeker.
+func (r *ByteRope) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.Size()
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	r.off = offset
+	return offset, nil
+}
@@ -0,0 +1,138 @@
+package tree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// fragmentedRope returns a ByteRope holding the given text, split into many short, out of order leaves.
+func fragmentedRope(text string) *ByteRope {
+	r := NewByteRope(nil)
+	for i := len(text); i > 0; i -= 3 {
+		r = r.Insert(0, []byte(text[max(0, i-3):i]))
+	}
+	return r
+}
+
+func TestByteRope(t *testing.T) {
+	text := "the quick brown fox jumps over the lazy dog"
+	r := fragmentedRope(text)
+	if s := r.String(); s != text {
+		t.Fatalf("Expected %q, got %q", text, s)
+	}
+	if err := iotest.TestReader(r, []byte(text)); err != nil {
+		t.Error(err)
+	}
+
+	t.Run("ReadAt", func(t *testing.T) {
+		p := make([]byte, 9)
+		if n, err := r.ReadAt(p, 4); n != 9 || err != nil || string(p) != "quick bro" {
+			t.Errorf("Expected 9 \"quick bro\" nil, got %d %q %v", n, p, err)
+		}
+		if n, err := r.ReadAt(p, int64(len(text)-3)); n != 3 || err != io.EOF || string(p[:n]) != "dog" {
+			t.Errorf("Expected 3 \"dog\" EOF, got %d %q %v", n, p[:n], err)
+		}
+		if _, err := r.ReadAt(p, -1); err == nil {
+			t.Error("Expected an error reading at a negative offset")
+		}
+	})
+
+	t.Run("WriteTo", func(t *testing.T) {
+		r.Seek(4, io.SeekStart)
+		var buf bytes.Buffer
+		if n, err := io.Copy(&buf, r); err != nil || n != int64(len(text)-4) || buf.String() != text[4:] {
+			t.Errorf("Expected %d %q, got %d %q %v", len(text)-4, text[4:], n, buf.String(), err)
+		}
+		if n, _ := r.WriteTo(&buf); n != 0 {
+			t.Errorf("Expected nothing left to write, got %d", n)
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		if pos, err := r.Seek(-3, io.SeekEnd); err != nil || pos != int64(len(text)-3) {
+			t.Errorf("Expected %d, got %d %v", len(text)-3, pos, err)
+		}
+		if pos, err := r.Seek(-1, io.SeekCurrent); err != nil || pos != int64(len(text)-4) {
+			t.Errorf("Expected %d, got %d %v", len(text)-4, pos, err)
+		}
+		if _, err := r.Seek(-1, io.SeekStart); err == nil {
+			t.Error("Expected an error seeking to a negative offset")
+		}
+		r.Seek(100, io.SeekStart)
+		if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+			t.Errorf("Expected 0 EOF reading past the end, got %d %v", n, err)
+		}
+	})
+
+	t.Run("bufio", func(t *testing.T) {
+		r := NewByteRope([]byte("one\ntwo\n")).Insert(4, []byte("one and a half\n"))
+		var lines []string
+		for sc := bufio.NewScanner(r); sc.Scan(); {
+			lines = append(lines, sc.Text())
+		}
+		if s := strings.Join(lines, ","); s != "one,one and a half,two" {
+			t.Errorf("Expected \"one,one and a half,two\", got %q", s)
+		}
+	})
+
+	t.Run("edits", func(t *testing.T) {
+		edited := r.Remove(4, 6).Insert(4, []byte("slow "))
+		if s := edited.String(); s != "the slow brown fox jumps over the lazy dog" {
+			t.Errorf("Unexpected %q", s)
+		}
+		if s := r.String(); s != text {
+			t.Errorf("Expected the original to be unchanged, got %q", s)
+		}
+	})
+}
+
+func TestByteRope_Text(t *testing.T) {
+	var _ encoding.TextMarshaler = &ByteRope{}
+	var _ encoding.TextUnmarshaler = &ByteRope{}
+
+	b, err := NewByteRope([]byte("hello")).Insert(5, []byte(" world")).MarshalText()
+	if err != nil || string(b) != "hello world" {
+		t.Errorf("Expected \"hello world\", got %q %v", b, err)
+	}
+	if b, err := new(ByteRope).MarshalText(); err != nil || len(b) != 0 {
+		t.Errorf("Expected the zero ByteRope to be empty, got %q %v", b, err)
+	}
+	text := []byte("abc")
+	var r ByteRope
+	if err := r.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	text[0] = 'x'
+	if s := r.String(); s != "abc" {
+		t.Errorf("Expected UnmarshalText to copy the text, got %q", s)
+	}
+}
+
+func TestByteRope_Zero(t *testing.T) {
+	var r ByteRope
+	if r.Len() != 0 || r.Size() != 0 || r.String() != "" || len(r.Bytes()) != 0 {
+		t.Errorf("Expected the zero ByteRope to be empty, got %q", r.String())
+	}
+	if err := iotest.TestReader(&r, nil); err != nil {
+		t.Error(err)
+	}
+	var sb strings.Builder
+	if n, err := r.WriteTo(&sb); n != 0 || err != nil {
+		t.Errorf("Expected to write nothing, got %d %v", n, err)
+	}
+	if s := r.Insert(0, []byte("abc")).String(); s != "abc" {
+		t.Errorf("Expected \"abc\", got %q", s)
+	}
+	if s := r.ReplaceAllRegexp(regexp.MustCompile(`x*`), []byte("-")).String(); s != "-" {
+		t.Errorf("Expected \"-\", got %q", s)
+	}
+	if r.Remove(0, 0).Len() != 0 {
+		t.Error("Expected removing nothing to leave it empty")
+	}
+}
@@ -0,0 +1,182 @@
+package tree
+
+import (
+	"encoding/binary"
+	"iter"
+	"slices"
+	"sync/atomic"
+	"unsafe"
+)
+
+// COMPRESSED_SLAB_BLOCK_SIZE is the number of items packed into each block of a CompressedSlab.
+const COMPRESSED_SLAB_BLOCK_SIZE = 128
+
+// Integer is the constraint for the element types a CompressedSlab can store.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// CompressedSlab is a Slab of integers which packs full blocks of items using delta and varint encoding, so
+// monotonic sequences (timestamps, ids, etc.) and small values take only a byte or two per item.
+// Items are appended to an open, uncompressed, block, which is compressed once it is full.
+// Reading from a compressed block decodes the whole block into a cache, so sequential reads are O(1) amortised.
+// The cache is replaced rather than overwritten, so concurrent reads are safe, though readers of different blocks
+// will keep decoding them again.
+type CompressedSlab[T Integer] struct {
+	packed  []byte
+	offsets []uint64
+	open    []T
+	cache   atomic.Pointer[decodedBlock[T]]
+}
+
+// decodedBlock is the cache of a CompressedSlab: the decoded items of one of its compressed blocks.
+type decodedBlock[T Integer] struct {
+	index int
+	items []T
+}
+
+// NewCompressedSlab creates a new, empty, CompressedSlab.
+func NewCompressedSlab[T Integer]() *CompressedSlab[T] {
+	return &CompressedSlab[T]{
+		open: make([]T, 0, COMPRESSED_SLAB_BLOCK_SIZE),
+	}
+}
+
+// encodeBlock appends the varint encoded deltas between consecutive items to buf.
+// The deltas are zig-zag encoded so that decreasing sequences and negative values stay small too.
+func encodeBlock[T Integer](buf []byte, items []T) []byte {
+	var prev uint64
+	for _, v := range items {
+		d := int64(uint64(v) - prev)
+		buf = binary.AppendUvarint(buf, uint64(d<<1)^uint64(d>>63))
+		prev = uint64(v)
+	}
+	return buf
+}
+
+// decodeBlock decodes a block produced by encodeBlock into items, which must be the length of the block.
+func decodeBlock[T Integer](buf []byte, items []T) {
+	var prev uint64
+	for i := range items {
+		z, n := binary.Uvarint(buf)
+		buf = buf[n:]
+		prev += z>>1 ^ -(z & 1)
+		items[i] = T(prev)
+	}
+}
+
+// seal compresses the open block and starts a new one.
+func (s *CompressedSlab[T]) seal() {
+	s.offsets = append(s.offsets, uint64(len(s.packed)))
+	s.packed = encodeBlock(s.packed, s.open)
+	s.open = s.open[:0]
+}
+
+// block returns the decoded items of the given block, which may be shared with other readers, so mustn't be modified.
+func (s *CompressedSlab[T]) block(b int) []T {
+	if b == len(s.offsets) {
+		return s.open
+	}
+	if c := s.cache.Load(); c != nil && c.index == b {
+		return c.items
+	}
+	items := make([]T, COMPRESSED_SLAB_BLOCK_SIZE)
+	decodeBlock(s.packed[s.offsets[b]:], items)
+	s.cache.Store(&decodedBlock[T]{b, items})
+	return items
+}
+
+func (s *CompressedSlab[T]) Add(items ...T) (start uint32, length uint32) {
+	start = s.Len()
+	length = checkedAdd(0, uint64(len(items)))
+	checkedAdd(start, uint64(length))
+	for len(items) > 0 {
+		n := min(len(items), COMPRESSED_SLAB_BLOCK_SIZE-len(s.open))
+		s.open = append(s.open, items[:n]...)
+		items = items[n:]
+		if len(s.open) == COMPRESSED_SLAB_BLOCK_SIZE {
+			s.seal()
+		}
+	}
+	return
+}
+
+func (s *CompressedSlab[T]) Get(index uint32) T {
+	return s.block(int(index / COMPRESSED_SLAB_BLOCK_SIZE))[index%COMPRESSED_SLAB_BLOCK_SIZE]
+}
+
+// GetRef returns a reference to a copy of the item at the given index, as compressed items have no address of
+// their own. Writing through the reference does not change the slab.
+func (s *CompressedSlab[T]) GetRef(index uint32) *T {
+	v := s.Get(index)
+	return &v
+}
+
+// Set replaces the item at the given index. Items in the open block are replaced in place, but replacing an
+// item in a compressed block means re-encoding the block and shifting every block after it, so it is O(n).
+func (s *CompressedSlab[T]) Set(index uint32, item T) {
+	b := int(index / COMPRESSED_SLAB_BLOCK_SIZE)
+	if b == len(s.offsets) {
+		s.open[index%COMPRESSED_SLAB_BLOCK_SIZE] = item
+		return
+	}
+	items := slices.Clone(s.block(b))
+	items[index%COMPRESSED_SLAB_BLOCK_SIZE] = item
+	start, end := s.offsets[b], uint64(len(s.packed))
+	if b+1 < len(s.offsets) {
+		end = s.offsets[b+1]
+	}
+	enc := encodeBlock(nil, items)
+	s.packed = append(s.packed[:start], append(enc, s.packed[end:]...)...)
+	for i := b + 1; i < len(s.offsets); i++ {
+		s.offsets[i] = s.offsets[i] - (end - start) + uint64(len(enc))
+	}
+	s.cache.Store(&decodedBlock[T]{b, items})
+}
+
+func (s *CompressedSlab[T]) Len() uint32 {
+	return uint32(len(s.offsets)*COMPRESSED_SLAB_BLOCK_SIZE + len(s.open))
+}
+
+// SliceIter iterates over length items from the start index. It decodes blocks into its own buffers, so it
+// doesn't disturb the cache used by Get.
+func (s *CompressedSlab[T]) SliceIter(start uint32, length uint32) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for span := range s.SpanIter(start, length) {
+			for _, v := range span {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SpanIter iterates over length items from the start index, yielding a decoded copy of the part of each block the
+// range covers.
+func (s *CompressedSlab[T]) SpanIter(start uint32, length uint32) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		for length > 0 {
+			b, o := int(start/COMPRESSED_SLAB_BLOCK_SIZE), start%COMPRESSED_SLAB_BLOCK_SIZE
+			items := make([]T, COMPRESSED_SLAB_BLOCK_SIZE)
+			if b < len(s.offsets) {
+				decodeBlock(s.packed[s.offsets[b]:], items)
+			} else {
+				items = append(items[:0], s.open...)
+			}
+			span := items[o:min(uint32(len(items)), o+length)]
+			if !yield(span) {
+				return
+			}
+			start += uint32(len(span))
+			length -= uint32(len(span))
+		}
+	}
+}
+
+// Size returns the approximate number of bytes used to store the items in the slab, for comparison with the
+// size of an uncompressed slab.
+func (s *CompressedSlab[T]) Size() int {
+	var zero T
+	return len(s.packed) + 8*len(s.offsets) + len(s.open)*int(unsafe.Sizeof(zero))
+}
@@ -0,0 +1,156 @@
+package tree
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestCompressedSlab_Get(t *testing.T) {
+	s := NewCompressedSlab[int64]()
+	values := []int64{0, 1, -1, math.MaxInt64, math.MinInt64, 42}
+	for i := 0; i < 100; i++ {
+		s.Add(values...)
+	}
+	if s.Len() != uint32(100*len(values)) {
+		t.Error("Expected", 100*len(values), "got", s.Len())
+	}
+	for i := uint32(0); i < s.Len(); i++ {
+		if s.Get(i) != values[int(i)%len(values)] {
+			t.Error("Expected", values[int(i)%len(values)], "got", s.Get(i), "at", i)
+		}
+	}
+}
+
+func TestCompressedSlab_Unsigned(t *testing.T) {
+	s := NewCompressedSlab[uint64]()
+	for i := uint64(0); i < 1000; i++ {
+		s.Add(math.MaxUint64 - i*i)
+	}
+	for i := uint32(0); i < 1000; i += 7 {
+		if s.Get(i) != math.MaxUint64-uint64(i)*uint64(i) {
+			t.Error("Expected", math.MaxUint64-uint64(i)*uint64(i), "got", s.Get(i))
+		}
+	}
+}
+
+func TestCompressedSlab_SliceIter(t *testing.T) {
+	s := NewCompressedSlab[uint32]()
+	for i := uint32(0); i < 1000; i++ {
+		s.Add(i)
+	}
+	i := uint32(100)
+	for x := range s.SliceIter(100, 850) {
+		if x != i {
+			t.Error("Expected", i, "got", x)
+		}
+		i++
+	}
+	if i != 950 {
+		t.Error("Expected to stop at 950, stopped at", i)
+	}
+}
+
+// compressionRatio reports how many times smaller a CompressedSlab is than a MinimalSlab holding the same items.
+func compressionRatio[T Integer](items []T) float64 {
+	s := NewCompressedSlab[T]()
+	s.Add(items...)
+	var zero T
+	return float64(len(items)*int(unsafe.Sizeof(zero))) / float64(s.Size())
+}
+
+func TestCompressedSlab_Ratio(t *testing.T) {
+	timestamps := make([]int64, 1<<16)
+	ts := int64(1_700_000_000_000)
+	for i := range timestamps {
+		ts += rand.Int63n(1000)
+		timestamps[i] = ts
+	}
+	ratio := compressionRatio(timestamps)
+	t.Logf("timestamp compression ratio vs MinimalSlab: %.2f", ratio)
+	if ratio < 3 {
+		t.Error("Expected timestamps to compress at least 3x, got", ratio)
+	}
+}
+
+func BenchmarkCompressedSlab(b *testing.B) {
+	ids := make([]uint32, 1<<16)
+	for i := range ids {
+		ids[i] = uint32(i)
+	}
+
+	b.Run("add", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			s := NewCompressedSlab[uint32]()
+			s.Add(ids...)
+		}
+		b.ReportMetric(compressionRatio(ids), "ratio")
+	})
+
+	b.Run("get sequential", func(b *testing.B) {
+		s := NewCompressedSlab[uint32]()
+		s.Add(ids...)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			_ = s.Get(uint32(n) % s.Len())
+		}
+	})
+
+	b.Run("get minimal", func(b *testing.B) {
+		s := MinimalSlab[uint32]{}
+		s.Add(ids...)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			_ = s.Get(uint32(n) % s.Len())
+		}
+	})
+}
+
+func TestCompressedSlab_Set(t *testing.T) {
+	s := NewCompressedSlab[int32]()
+	for i := int32(0); i < 1000; i++ {
+		s.Add(i)
+	}
+	s.Set(5, 1<<30)
+	s.Set(200, -7)
+	s.Set(999, 0)
+	for i := int32(0); i < 1000; i++ {
+		expect := i
+		switch i {
+		case 5:
+			expect = 1 << 30
+		case 200:
+			expect = -7
+		case 999:
+			expect = 0
+		}
+		if s.Get(uint32(i)) != expect {
+			t.Error("Expected", expect, "got", s.Get(uint32(i)), "at", i)
+		}
+	}
+}
+
+func TestCompressedSlab_ConcurrentGet(t *testing.T) {
+	s := NewCompressedSlab[uint32]()
+	for i := uint32(0); i < 10*COMPRESSED_SLAB_BLOCK_SIZE+5; i++ {
+		s.Add(i * 3)
+	}
+	var wg sync.WaitGroup
+	for w := range uint32(4) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each reader starts from a different block, so they keep replacing each other's cache
+			for k := range s.Len() {
+				i := (k + w*3*COMPRESSED_SLAB_BLOCK_SIZE) % s.Len()
+				if v := s.Get(i); v != i*3 {
+					t.Error("Expected", i*3, "got", v)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
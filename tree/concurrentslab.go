@@ -0,0 +1,140 @@
+package tree
+
+import (
+	"iter"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// CONCURRENT_SLAB_FIRST_BUCKET_BITS is log2 of the number of items in the first bucket of a ConcurrentSlab.
+// Each subsequent bucket is twice the size of the one before it.
+const CONCURRENT_SLAB_FIRST_BUCKET_BITS = 6
+
+// concurrentSlabBuckets is the number of buckets needed to address every uint32 index.
+const concurrentSlabBuckets = 33 - CONCURRENT_SLAB_FIRST_BUCKET_BITS
+
+// ConcurrentSlab is a Slab which can be safely added to from many goroutines at once.
+// Items are stored in buckets of doubling size which are never moved once allocated, so writers reserve their
+// range of indices with a single atomic compare-and-swap, and readers never need to take a lock.
+// Each writer publishes its own range when it has copied its items in, without waiting on any other writer, and Len
+// covers every range up to the first which is still being written.
+// Only items at indices below Len, or returned by a completed call to Add, are safe to read.
+type ConcurrentSlab[T any] struct {
+	buckets [concurrentSlabBuckets]atomic.Pointer[[]T]
+	// pending maps the start index of each range which has been written but not yet published to the index just past
+	// its end, so it only ever holds a record for each Add still waiting on an earlier one.
+	pending   sync.Map
+	reserved  atomic.Uint32
+	published atomic.Uint32
+}
+
+// NewConcurrentSlab creates a new, empty, ConcurrentSlab.
+func NewConcurrentSlab[T any]() *ConcurrentSlab[T] {
+	return &ConcurrentSlab[T]{}
+}
+
+// locate returns the bucket and offset within that bucket of the given index.
+func (s *ConcurrentSlab[T]) locate(index uint32) (bucket int, offset uint64) {
+	pos := uint64(index) + 1<<CONCURRENT_SLAB_FIRST_BUCKET_BITS
+	bucket = bits.Len64(pos) - 1 - CONCURRENT_SLAB_FIRST_BUCKET_BITS
+	offset = pos - 1<<(bucket+CONCURRENT_SLAB_FIRST_BUCKET_BITS)
+	return
+}
+
+// bucket returns the given bucket, allocating it if no other goroutine has done so already.
+func (s *ConcurrentSlab[T]) bucket(b int) []T {
+	if p := s.buckets[b].Load(); p != nil {
+		return *p
+	}
+	nb := make([]T, 1<<(b+CONCURRENT_SLAB_FIRST_BUCKET_BITS))
+	if s.buckets[b].CompareAndSwap(nil, &nb) {
+		return nb
+	}
+	return *s.buckets[b].Load()
+}
+
+// Add atomically reserves a range of indices for the items and copies them into the slab, then publishes them.
+// It panics with ErrIndexOverflow, without reserving anything, if the range would pass the last addressable index.
+// The items are counted by Len once every earlier range has also been written, but Add never waits for that; the
+// last writer to finish a contiguous run of ranges publishes all of them. Readers never wait.
+func (s *ConcurrentSlab[T]) Add(items ...T) (start uint32, length uint32) {
+	length = checkedAdd(0, uint64(len(items)))
+	for {
+		start = s.reserved.Load()
+		if s.reserved.CompareAndSwap(start, checkedAdd(start, uint64(length))) {
+			break
+		}
+	}
+	if length == 0 {
+		return
+	}
+	for i := start; len(items) > 0; {
+		b, o := s.locate(i)
+		n := copy(s.bucket(b)[o:], items)
+		items = items[n:]
+		i += uint32(n)
+	}
+	s.publish(start, length)
+	return
+}
+
+// publish records the range of length items from the start index as written, then advances published past it and
+// any written ranges after it if every range before it has been published.
+// A writer which finds an earlier range still unwritten leaves advancing to that range's writer, which will find
+// this range's record when it advances to it, as the record is stored before the check of published.
+func (s *ConcurrentSlab[T]) publish(start, length uint32) {
+	s.pending.Store(start, start+length)
+	for {
+		p := s.published.Load()
+		end, ok := s.pending.Load(p)
+		if !ok {
+			return
+		}
+		// if this fails another writer has advanced published, so just look again from where it is now
+		if s.published.CompareAndSwap(p, end.(uint32)) {
+			s.pending.Delete(p)
+		}
+	}
+}
+
+func (s *ConcurrentSlab[T]) Get(index uint32) T {
+	return *s.GetRef(index)
+}
+
+// GetRef returns a reference to the item at the given index. As buckets are never moved the reference remains
+// valid for the lifetime of the slab, but writes through it are not synchronised with readers.
+func (s *ConcurrentSlab[T]) GetRef(index uint32) *T {
+	b, o := s.locate(index)
+	return &(*s.buckets[b].Load())[o]
+}
+
+// Set replaces the item at the given index. Like GetRef, it is not synchronised with readers of that index.
+func (s *ConcurrentSlab[T]) Set(index uint32, item T) {
+	*s.GetRef(index) = item
+}
+
+// Len returns the number of published items in the slab.
+func (s *ConcurrentSlab[T]) Len() uint32 {
+	return s.published.Load()
+}
+
+func (s *ConcurrentSlab[T]) SliceIter(start uint32, length uint32) iter.Seq[T] {
+	return getIter(s, start, length)
+}
+
+// SpanIter iterates over length items from the start index, yielding a view of each bucket the range covers.
+func (s *ConcurrentSlab[T]) SpanIter(start uint32, length uint32) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		for length > 0 {
+			b, o := s.locate(start)
+			bucket := (*s.buckets[b].Load())[o:]
+			n := min(uint64(length), uint64(len(bucket)))
+			if !yield(bucket[:n:n]) {
+				return
+			}
+			start += uint32(n)
+			length -= uint32(n)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package tree
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentSlab_Add(t *testing.T) {
+	s := NewConcurrentSlab[int]()
+	start, length := s.Add(1, 2, 3)
+	if start != 0 {
+		t.Error("Expected start 0, got", start)
+	}
+	if length != 3 {
+		t.Error("Expected length 3, got", length)
+	}
+	start, _ = s.Add(make([]int, 1000)...)
+	if start != 3 {
+		t.Error("Expected start 3, got", start)
+	}
+	if s.Len() != 1003 {
+		t.Error("Expected 1003, got", s.Len())
+	}
+}
+
+func TestConcurrentSlab_StalledWriter(t *testing.T) {
+	s := NewConcurrentSlab[int]()
+	// reserve a range as a writer would, without writing it yet
+	s.reserved.Store(5)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if start, _ := s.Add(6, 7); start != 5 {
+			t.Error("Expected start 5, got", start)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected Add not to wait on an earlier writer")
+	}
+	if s.Len() != 0 {
+		t.Error("Expected nothing published past the unwritten range, got", s.Len())
+	}
+
+	// the stalled writer finishing publishes the later range too
+	for i := range uint32(5) {
+		s.Set(i, int(i+1))
+	}
+	s.publish(0, 5)
+	if s.Len() != 7 {
+		t.Error("Expected 7, got", s.Len())
+	}
+	for i := range uint32(7) {
+		if s.Get(i) != int(i+1) {
+			t.Error("Expected", i+1, "got", s.Get(i))
+		}
+	}
+}
+
+func TestConcurrentSlab_Overflow(t *testing.T) {
+	s := NewConcurrentSlab[int]()
+	s.reserved.Store(math.MaxUint32 - 1)
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrIndexOverflow) {
+				t.Error("Expected ErrIndexOverflow, got", err)
+			}
+		}()
+		s.Add(1, 2)
+	}()
+	if s.reserved.Load() != math.MaxUint32-1 {
+		t.Error("Expected nothing to be reserved, got", s.reserved.Load())
+	}
+}
+
+func TestConcurrentSlab_SliceIter(t *testing.T) {
+	s := NewConcurrentSlab[int]()
+	for i := 0; i < 200; i++ {
+		s.Add(i)
+	}
+	i := 50
+	for x := range s.SliceIter(50, 100) {
+		if x != i {
+			t.Error("Expected", i, "got", x)
+		}
+		i++
+	}
+}
+
+func TestConcurrentSlab_Stress(t *testing.T) {
+	const writers, adds, batch = 8, 500, 7
+	s := NewConcurrentSlab[uint32]()
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// readers check every published item while the writers are running
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				n := s.Len()
+				for i := uint32(0); i < n; i++ {
+					if v := s.Get(i); v>>16 >= writers {
+						t.Error("Unexpected value", v, "at", i)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	var writing sync.WaitGroup
+	for w := uint32(0); w < writers; w++ {
+		writing.Add(1)
+		go func() {
+			defer writing.Done()
+			items := make([]uint32, batch)
+			for i := range items {
+				items[i] = w << 16
+			}
+			for a := 0; a < adds; a++ {
+				start, length := s.Add(items...)
+				for i := start; i < start+length; i++ {
+					if s.Get(i) != w<<16 {
+						t.Error("Expected", w<<16, "got", s.Get(i))
+					}
+				}
+			}
+		}()
+	}
+	writing.Wait()
+	close(done)
+	wg.Wait()
+
+	if s.Len() != writers*adds*batch {
+		t.Error("Expected", writers*adds*batch, "got", s.Len())
+	}
+	s.pending.Range(func(start, _ any) bool {
+		t.Error("Expected no ranges left pending, got one at", start)
+		return true
+	})
+	counts := make([]int, writers)
+	for i := uint32(0); i < s.Len(); i++ {
+		counts[s.Get(i)>>16]++
+	}
+	for w, c := range counts {
+		if c != adds*batch {
+			t.Error("writer", w, "expected", adds*batch, "items, got", c)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package tree
+
+// A Cursor is a position within the (sub)tree of a TreeSlab rooted at a given node index.
+// It keeps the path from the root to the current leaf, so moving to the next or previous position is amortised O(1),
+// and only Seek needs to descend from the root.
+// As nodes never change once added, edits to the TreeSlab never invalidate a Cursor; it carries on seeing the tree it
+// was created on. Rebase moves the Cursor onto the new root an edit returns.
+type Cursor struct {
+	ts   *TreeSlab
+	root uint32
+	// path is the stack of branches between the root and the current leaf.
+	path []cursorStep
+	leaf node
+	// start is the position of the first item of the current leaf, and offset the position within it.
+	start, offset uint32
+	valid         bool
+}
+
+// cursorStep records a branch on the path to the current leaf, and which of its children the path continued into.
+type cursorStep struct {
+	index uint32
+	right bool
+}
+
+// NewCursor returns a Cursor over the (sub)tree rooted at the given node index, positioned at its first item.
+// If the tree is empty the Cursor is not Valid.
+func (ts *TreeSlab) NewCursor(root uint32) *Cursor {
+	c := &Cursor{ts: ts, root: root}
+	c.Seek(0)
+	return c
+}
+
+// Valid reports whether the Cursor is positioned at an item.
+// A Cursor is only invalid if its tree is empty, or after a Seek past the end.
+func (c *Cursor) Valid() bool {
+	return c.valid
+}
+
+// Pos returns the position of the Cursor within its tree.
+func (c *Cursor) Pos() uint32 {
+	return c.start + c.offset
+}
+
+// Index returns the data slab index of the item at the Cursor.
+func (c *Cursor) Index() uint32 {
+	return c.leaf.x + c.offset
+}
+
+// Root returns the node index of the root of the tree the Cursor is over.
+func (c *Cursor) Root() uint32 {
+	return c.root
+}
+
+// Seek moves the Cursor to the given position, descending from the root in O(log n).
+// It returns false, leaving the Cursor invalid, if the position is past the end of the tree.
+func (c *Cursor) Seek(pos uint32) bool {
+	c.path = c.path[:0]
+	c.start, c.offset = pos, 0
+	if c.valid = pos < c.ts.Len(c.root); !c.valid {
+		return false
+	}
+	c.start = 0
+	index := c.root
+	for {
+		n := c.ts.nodes.Get(index)
+		if n.leaf {
+			c.leaf = n
+			c.offset = pos - c.start
+			return true
+		}
+		if l := c.ts.Len(n.x); pos-c.start < l {
+			c.path = append(c.path, cursorStep{index, false})
+			index = n.x
+		} else {
+			c.start += l
+			c.path = append(c.path, cursorStep{index, true})
+			index = n.y
+		}
+	}
+}
+
+// Next moves the Cursor to the next position in amortised O(1).
+// It returns false, leaving the Cursor where it was, if there is no next position.
+func (c *Cursor) Next() bool {
+	if !c.valid || c.Pos()+1 >= c.ts.Len(c.root) {
+		return false
+	}
+	if c.offset+1 < c.leaf.y {
+		c.offset++
+		return true
+	}
+	for {
+		c.start += c.leaf.y
+		// climb to the nearest branch whose right child hasn't been visited, then descend to its leftmost leaf
+		for c.path[len(c.path)-1].right {
+			c.path = c.path[:len(c.path)-1]
+		}
+		s := &c.path[len(c.path)-1]
+		s.right = true
+		index := c.ts.nodes.Get(s.index).y
+		for n := c.ts.nodes.Get(index); !n.leaf; n = c.ts.nodes.Get(index) {
+			c.path = append(c.path, cursorStep{index, false})
+			index = n.x
+		}
+		c.leaf, c.offset = c.ts.nodes.Get(index), 0
+		if c.leaf.y > 0 {
+			return true
+		}
+	}
+}
+
+// Prev moves the Cursor to the previous position in amortised O(1).
+// It returns false, leaving the Cursor where it was, if there is no previous position.
+func (c *Cursor) Prev() bool {
+	if !c.valid || c.Pos() == 0 {
+		return false
+	}
+	if c.offset > 0 {
+		c.offset--
+		return true
+	}
+	for {
+		// climb to the nearest branch whose left child hasn't been visited, then descend to its rightmost leaf
+		for !c.path[len(c.path)-1].right {
+			c.path = c.path[:len(c.path)-1]
+		}
+		s := &c.path[len(c.path)-1]
+		s.right = false
+		index := c.ts.nodes.Get(s.index).x
+		for n := c.ts.nodes.Get(index); !n.leaf; n = c.ts.nodes.Get(index) {
+			c.path = append(c.path, cursorStep{index, true})
+			index = n.y
+		}
+		c.leaf = c.ts.nodes.Get(index)
+		c.start -= c.leaf.y
+		if c.leaf.y > 0 {
+			c.offset = c.leaf.y - 1
+			return true
+		}
+	}
+}
+
+// Rebase moves the Cursor onto a new root, such as one returned by an edit, at the same position it had before, or
+// the last position of the new tree if that is shorter.
+// It returns false, leaving the Cursor invalid, if the new tree is empty.
+func (c *Cursor) Rebase(root uint32) bool {
+	pos := c.Pos()
+	c.root = root
+	if l := c.ts.Len(root); pos >= l && l > 0 {
+		pos = l - 1
+	}
+	return c.Seek(pos)
+}
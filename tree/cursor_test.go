@@ -0,0 +1,104 @@
+package tree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	ts, idx := generateUnbalancedTree(5, 2, 0)
+	// splice in some empty leaves, which the cursor has to step over
+	idx = ts.insert(idx, 9, ts.AddLeaf(0, 0))
+	idx = ts.insert(idx, 9, ts.AddLeaf(0, 0))
+	idx = ts.addBranch(ts.AddLeaf(0, 0), idx)
+	var all []uint32
+	for i := range ts.IndexIter(idx) {
+		all = append(all, i)
+	}
+
+	t.Run("next", func(t *testing.T) {
+		c := ts.NewCursor(idx)
+		i := 0
+		for ok := c.Valid(); ok; ok = c.Next() {
+			if c.Pos() != uint32(i) || c.Index() != all[i] {
+				t.Fatal("Expected", i, all[i], "got", c.Pos(), c.Index())
+			}
+			i++
+		}
+		if i != len(all) {
+			t.Error("Expected", len(all), "positions, got", i)
+		}
+		if c.Pos() != uint32(len(all)-1) {
+			t.Error("Expected a failed Next to leave the cursor in place")
+		}
+	})
+
+	t.Run("prev", func(t *testing.T) {
+		c := ts.NewCursor(idx)
+		c.Seek(uint32(len(all) - 1))
+		i := len(all) - 1
+		for ok := c.Valid(); ok; ok = c.Prev() {
+			if c.Pos() != uint32(i) || c.Index() != all[i] {
+				t.Fatal("Expected", i, all[i], "got", c.Pos(), c.Index())
+			}
+			i--
+		}
+		if i != -1 {
+			t.Error("Expected to stop at -1, stopped at", i)
+		}
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		c := ts.NewCursor(idx)
+		for n := 0; n < 100; n++ {
+			p := rand.Intn(len(all))
+			if !c.Seek(uint32(p)) || c.Index() != all[p] {
+				t.Fatal("Expected", all[p], "at", p, "got", c.Index())
+			}
+			if p+1 < len(all) && (!c.Next() || c.Index() != all[p+1]) {
+				t.Error("Expected", all[p+1], "after", p, "got", c.Index())
+			}
+		}
+		if c.Seek(uint32(len(all))) || c.Valid() {
+			t.Error("Expected seeking past the end to invalidate the cursor")
+		}
+	})
+
+	t.Run("rebase", func(t *testing.T) {
+		ts := NewTreeSlab()
+		root := ts.AddLeaf(0, 10)
+		c := ts.NewCursor(root)
+		c.Seek(7)
+		edited := *ts.Remove(root, 2, 3)
+		if c.Index() != 7 {
+			t.Error("Expected the cursor to be unaffected by the edit")
+		}
+		if !c.Rebase(edited) || c.Pos() != 6 || c.Index() != 9 {
+			t.Error("Expected the cursor to clamp to 6:9, got", c.Pos(), c.Index())
+		}
+		c.Seek(1)
+		c.Rebase(*ts.Remove(edited, 0, 1))
+		if c.Pos() != 1 || c.Index() != 5 {
+			t.Error("Expected 1:5, got", c.Pos(), c.Index())
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		ts := NewTreeSlab()
+		c := ts.NewCursor(ts.AddLeaf(0, 0))
+		if c.Valid() || c.Next() || c.Prev() {
+			t.Error("Expected a cursor over an empty tree to be invalid")
+		}
+	})
+}
+
+func BenchmarkCursor(b *testing.B) {
+	ts, idx := generateBalancedTree(12, 4)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := ts.NewCursor(idx)
+		for ok := c.Valid(); ok; ok = c.Next() {
+			_ = c.Index()
+		}
+	}
+}
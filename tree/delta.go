@@ -0,0 +1,177 @@
+package tree
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+)
+
+// DELTA_MAGIC identifies a delta encoded by ExportSince.
+const DELTA_MAGIC = "SADL"
+
+// DELTA_FORMAT_VERSION is the version of the encoding written by ExportSince.
+const DELTA_FORMAT_VERSION = 1
+
+// deltaHeaderSize is the size of the encoded header: magic, version, flags, node and data marks, base fingerprint,
+// root, and node count.
+const deltaHeaderSize = 4 + 2 + 2 + 4 + 4 + 4 + 4 + 4
+
+// deltaMinSize is the size of an encoded delta with no nodes or items: the header, item count, encoded items length,
+// and checksum. Codecs may encode no items as no bytes.
+const deltaMinSize = deltaHeaderSize + 4 + 8 + 4
+
+// baseFingerprint returns the CRC-32 of the records of the nodes before nodeMark, followed by the items before
+// dataMark encoded by codec a chunk of SPLICE_ARRAY_CHUNK_SIZE at a time, so a replica which has diverged from the
+// exporter anywhere before the marks rejects the delta. Both prefixes are read in full, but a chunk at a time.
+func baseFingerprint[T any](sa SpliceArray[T], codec ElementCodec[T], nodeMark, dataMark uint32) (uint32, error) {
+	crc := crc32.NewIEEE()
+	buf := make([]byte, 0, SLAB_CHUNK_SIZE)
+	for i := uint32(0); i < nodeMark; i++ {
+		if len(buf)+NODE_RECORD_SIZE > cap(buf) {
+			crc.Write(buf)
+			buf = buf[:0]
+		}
+		buf = appendNode(buf, sa.tree.nodes.Get(i))
+	}
+	crc.Write(buf)
+	items := make([]T, 0, min(dataMark, SPLICE_ARRAY_CHUNK_SIZE))
+	for start := uint32(0); start < dataMark; start += uint32(len(items)) {
+		items = items[:0]
+		for span := range sa.data.SpanIter(start, min(dataMark-start, SPLICE_ARRAY_CHUNK_SIZE)) {
+			items = append(items, span...)
+		}
+		encoded, err := codec.Encode(items)
+		if err != nil {
+			return 0, err
+		}
+		crc.Write(encoded)
+	}
+	return crc.Sum32(), nil
+}
+
+// ExportSince encodes everything appended to the SpliceArray's TreeSlab and data slab since they held nodeMark nodes
+// and dataMark items, along with the SpliceArray's root. As the slabs are append-only, this is all a replica holding
+// the same first nodeMark nodes and dataMark items needs to catch up, using ApplyDelta.
+// The delta is checksummed, and records the marks and a fingerprint of the nodes and items before them, so it can
+// only be applied to a matching replica.
+func ExportSince[T any](sa SpliceArray[T], codec ElementCodec[T], nodeMark, dataMark uint32) ([]byte, error) {
+	nodeLen, dataLen := sa.tree.nodes.Len(), sa.data.Len()
+	if nodeMark > nodeLen || dataMark > dataLen {
+		return nil, fmt.Errorf("checkpoint %d:%d is beyond the end of the slabs %d:%d", nodeMark, dataMark, nodeLen, dataLen)
+	}
+
+	buf := append([]byte(DELTA_MAGIC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(buf[4:], DELTA_FORMAT_VERSION)
+	buf = binary.LittleEndian.AppendUint32(buf, nodeMark)
+	buf = binary.LittleEndian.AppendUint32(buf, dataMark)
+	fingerprint, err := baseFingerprint(sa, codec, nodeMark, dataMark)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, fingerprint)
+	buf = binary.LittleEndian.AppendUint32(buf, sa.root)
+	buf = binary.LittleEndian.AppendUint32(buf, nodeLen-nodeMark)
+	for i := nodeMark; i < nodeLen; i++ {
+		buf = appendNode(buf, sa.tree.nodes.Get(i))
+	}
+
+	items := make([]T, 0, dataLen-dataMark)
+	for span := range sa.data.SpanIter(dataMark, dataLen-dataMark) {
+		items = append(items, span...)
+	}
+	encoded, err := codec.Encode(items)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(items)))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(encoded)))
+	buf = append(buf, encoded...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf)), nil
+}
+
+// ApplyDelta appends the nodes and items of a delta encoded by ExportSince to the slabs of a replica, returning the
+// replica's SpliceArray at the exported root.
+// The delta is rejected, leaving the replica unchanged, if it is corrupt, or if the replica's slabs don't hold exactly
+// the nodes and items the delta was exported since, which are fingerprinted in full, so applying a delta reads the
+// whole of both slabs.
+func ApplyDelta[T any](replica SpliceArray[T], codec ElementCodec[T], delta []byte) (SpliceArray[T], error) {
+	if len(delta) < deltaMinSize || string(delta[:4]) != DELTA_MAGIC {
+		return replica, fmt.Errorf("data is not an encoded delta")
+	}
+	body := delta[:len(delta)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(delta[len(body):]) {
+		return replica, fmt.Errorf("delta checksum mismatch")
+	}
+	if v := binary.LittleEndian.Uint16(body[4:]); v == 0 || v > DELTA_FORMAT_VERSION {
+		return replica, fmt.Errorf("unsupported delta format version %d", v)
+	}
+	nodeMark := binary.LittleEndian.Uint32(body[8:])
+	dataMark := binary.LittleEndian.Uint32(body[12:])
+	if nodeMark != replica.tree.nodes.Len() || dataMark != replica.data.Len() {
+		return replica, fmt.Errorf("delta base %d:%d doesn't match replica %d:%d",
+			nodeMark, dataMark, replica.tree.nodes.Len(), replica.data.Len())
+	}
+	if fingerprint, err := baseFingerprint(replica, codec, nodeMark, dataMark); err != nil {
+		return replica, err
+	} else if binary.LittleEndian.Uint32(body[16:]) != fingerprint {
+		return replica, fmt.Errorf("delta base fingerprint doesn't match replica")
+	}
+	root := binary.LittleEndian.Uint32(body[20:])
+	count := binary.LittleEndian.Uint32(body[24:])
+
+	rest := body[deltaHeaderSize:]
+	if uint64(len(rest)) < uint64(count)*NODE_RECORD_SIZE+12 {
+		return replica, fmt.Errorf("delta is truncated")
+	}
+	nodes := make([]node, count)
+	for i := range nodes {
+		n, err := decodeNode(rest[uint64(i)*NODE_RECORD_SIZE:], nodeMark+uint32(i))
+		if err != nil {
+			return replica, err
+		}
+		nodes[i] = n
+	}
+	rest = rest[uint64(count)*NODE_RECORD_SIZE:]
+	itemCount := binary.LittleEndian.Uint32(rest)
+	if binary.LittleEndian.Uint64(rest[4:]) != uint64(len(rest)-12) {
+		return replica, fmt.Errorf("delta items have the wrong length")
+	}
+	items, err := codec.Decode(rest[12:])
+	if err != nil {
+		return replica, err
+	}
+	if uint32(len(items)) != itemCount {
+		return replica, fmt.Errorf("expected %d items, decoded %d", itemCount, len(items))
+	}
+	if uint64(root) >= uint64(nodeMark)+uint64(count) {
+		return replica, fmt.Errorf("delta root %d is out of range", root)
+	}
+	// measure the new nodes up front, so one which would overflow is rejected before anything is added
+	lengths := make([]uint64, count)
+	length := func(i uint32) uint64 {
+		if i < nodeMark {
+			return uint64(replica.tree.Len(i))
+		}
+		return lengths[i-nodeMark]
+	}
+	for i, n := range nodes {
+		if n.leaf {
+			if uint64(n.x)+uint64(n.y) > uint64(dataMark)+uint64(itemCount) {
+				return replica, fmt.Errorf("delta leaf refers to items beyond the end of the data slab")
+			}
+			lengths[i] = uint64(n.y)
+			continue
+		}
+		if lengths[i] = length(n.x) + length(n.y); lengths[i] > math.MaxUint32 {
+			return replica, fmt.Errorf("delta branch %d: %w", nodeMark+uint32(i), ErrIndexOverflow)
+		}
+	}
+
+	replica.data.Add(items...)
+	for _, n := range nodes {
+		replica.tree.addNode(n.leaf, n.x, n.y)
+	}
+	replica.root = root
+	return replica, nil
+}
@@ -0,0 +1,112 @@
+package tree
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// replicate returns a copy of a SpliceArray with its own slabs, as a replica in another process would have.
+func replicate[T any](t *testing.T, sa SpliceArray[T]) SpliceArray[T] {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := sa.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var replica SpliceArray[T]
+	if _, err := replica.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return replica
+}
+
+func TestDelta(t *testing.T) {
+	primary := NewSpliceArray([]byte("hello world")...)
+	replica := replicate(t, primary)
+	nodeMark, dataMark := primary.tree.nodes.Len(), primary.data.Len()
+
+	primary = primary.Insert(5, []byte(", cruel")...).Remove(0, 1).Insert(0, 'j')
+	delta, err := ExportSince(primary, DefaultCodec[byte](), nodeMark, dataMark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := ApplyDelta(replica, DefaultCodec[byte](), delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(slices.Collect(updated.All())); s != "jello, cruel world" {
+		t.Errorf("Expected \"jello, cruel world\", got %q", s)
+	}
+	if updated.tree.nodes.Len() != primary.tree.nodes.Len() || updated.data.Len() != primary.data.Len() {
+		t.Error("Expected the replica's slabs to match the primary's")
+	}
+
+	t.Run("reapplied", func(t *testing.T) {
+		if _, err := ApplyDelta(updated, DefaultCodec[byte](), delta); err == nil {
+			t.Error("Expected a delta applied twice to be rejected")
+		}
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		diverged := NewSpliceArray([]byte("hello world")...)
+		diverged.tree.AddLeaf(0, 3)
+		diverged.tree.AddLeaf(0, 4)
+		d, _ := ExportSince(primary, DefaultCodec[byte](), nodeMark+2, dataMark)
+		if _, err := ApplyDelta(diverged, DefaultCodec[byte](), d); err == nil {
+			t.Error("Expected a delta to a diverged replica to be rejected")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		d, err := ExportSince(updated, DefaultCodec[byte](), updated.tree.nodes.Len(), updated.data.Len())
+		if err != nil {
+			t.Fatal(err)
+		}
+		same, err := ApplyDelta(updated, DefaultCodec[byte](), d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same.root != updated.root || same.tree.nodes.Len() != updated.tree.nodes.Len() {
+			t.Error("Expected an empty delta to change nothing")
+		}
+	})
+
+	t.Run("data diverged", func(t *testing.T) {
+		diverged := NewSpliceArray([]byte("jello world")...)
+		if _, err := ApplyDelta(diverged, DefaultCodec[byte](), delta); err == nil {
+			t.Error("Expected a delta to a replica with different items to be rejected")
+		}
+	})
+
+	t.Run("diverged early", func(t *testing.T) {
+		// the replica differs only in its first item, far before the marks
+		text := bytes.Repeat([]byte("hello world "), 1000)
+		base := NewSpliceArray(text...)
+		replica := replicate(t, base)
+		nodeMark, dataMark := base.tree.nodes.Len(), base.data.Len()
+		d, err := ExportSince(base.Insert(5, '!'), DefaultCodec[byte](), nodeMark, dataMark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text[0] = 'j'
+		diverged := NewSpliceArray(text...)
+		if _, err := ApplyDelta(diverged, DefaultCodec[byte](), d); err == nil {
+			t.Error("Expected a delta to a replica which diverged early to be rejected")
+		}
+		if _, err := ApplyDelta(replica, DefaultCodec[byte](), d); err != nil {
+			t.Error("Expected a matching replica to accept the delta, got", err)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		fresh := replicate(t, NewSpliceArray([]byte("hello world")...))
+		corrupt := append([]byte{}, delta...)
+		corrupt[len(corrupt)/2] ^= 0xff
+		if _, err := ApplyDelta(fresh, DefaultCodec[byte](), corrupt); err == nil {
+			t.Error("Expected a corrupt delta to be rejected")
+		}
+		if fresh.tree.nodes.Len() != nodeMark || fresh.data.Len() != dataMark {
+			t.Error("Expected a rejected delta to leave the replica unchanged")
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// DOTOptions configures the output of WriteDOTWith.
+type DOTOptions struct {
+	// Lengths labels each node with its length.
+	Lengths bool
+	// Depths labels each node with its least depth below any of the roots.
+	Depths bool
+}
+
+// dotNode records what WriteDOTWith has found out about a node reachable from the roots.
+type dotNode struct {
+	depth int
+	// roots is the number of roots the node is reachable from.
+	roots int
+}
+
+// WriteDOT writes the (sub)trees rooted at the given node indices to w as a Graphviz DOT digraph.
+// Branches are drawn as ellipses with edges to their left and right children, and leaves as boxes with the range of
+// the data slab they refer to. Nodes reachable from more than one root are filled, to show the structure they share.
+func (ts *TreeSlab) WriteDOT(w io.Writer, roots ...uint32) error {
+	return ts.WriteDOTWith(w, DOTOptions{}, roots...)
+}
+
+// WriteDOTWith writes the (sub)trees rooted at the given node indices to w in the same way as WriteDOT, with extra
+// labels set by opts.
+func (ts *TreeSlab) WriteDOTWith(w io.Writer, opts DOTOptions, roots ...uint32) error {
+	found := map[uint32]*dotNode{}
+	for _, root := range roots {
+		// nodes can be shared within a tree as well as between them, so only count each once per root, but walk below
+		// them again whenever they are reached by a shorter path, so their descendants get their least depth too
+		seen := map[uint32]int{}
+		stack := []walkFrame{{index: root}}
+		for len(stack) > 0 {
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			d, ok := found[f.index]
+			if !ok {
+				d = &dotNode{depth: f.depth}
+				found[f.index] = d
+			}
+			d.depth = min(d.depth, f.depth)
+			depth, ok := seen[f.index]
+			if ok && depth <= f.depth {
+				continue
+			}
+			if !ok {
+				d.roots++
+			}
+			seen[f.index] = f.depth
+			if n := ts.nodes.Get(f.index); !n.leaf {
+				stack = append(stack, walkFrame{index: n.y, depth: f.depth + 1}, walkFrame{index: n.x, depth: f.depth + 1})
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph TreeSlab {")
+	fmt.Fprintln(bw, "\tnode [fontname=\"monospace\"];")
+	for i, root := range roots {
+		fmt.Fprintf(bw, "\troot%d [shape=plaintext label=\"root %d\"];\n", i, i)
+		fmt.Fprintf(bw, "\troot%d -> n%d;\n", i, root)
+	}
+	indices := make([]uint32, 0, len(found))
+	for i := range found {
+		indices = append(indices, i)
+	}
+	slices.Sort(indices)
+	for _, i := range indices {
+		n, d := ts.nodes.Get(i), found[i]
+		label := fmt.Sprintf("%d: branch", i)
+		shape := "ellipse"
+		if n.leaf {
+			label = fmt.Sprintf("%d: leaf [%d, %d)", i, n.x, uint64(n.x)+uint64(n.y))
+			shape = "box"
+		}
+		if opts.Lengths {
+			label += fmt.Sprintf("\\nlen %d", ts.Len(i))
+		}
+		if opts.Depths {
+			label += fmt.Sprintf("\\ndepth %d", d.depth)
+		}
+		style := ""
+		if d.roots > 1 {
+			style = " style=filled fillcolor=lightblue"
+		}
+		fmt.Fprintf(bw, "\tn%d [shape=%s label=\"%s\"%s];\n", i, shape, label, style)
+		if !n.leaf {
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"L\"];\n", i, n.x)
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"R\"];\n", i, n.y)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
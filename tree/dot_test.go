@@ -0,0 +1,69 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	ts := NewTreeSlab()
+	a := ts.addBranch(ts.AddLeaf(0, 5), ts.AddLeaf(5, 5))
+	b := ts.insert(a, 5, ts.AddLeaf(10, 3))
+
+	var sb strings.Builder
+	if err := ts.WriteDOT(&sb, a, b); err != nil {
+		t.Fatal(err)
+	}
+	dot := sb.String()
+	for _, want := range []string{
+		"digraph TreeSlab {",
+		"root0 -> n2;",
+		"root1 -> n5;",
+		"n0 [shape=box label=\"0: leaf [0, 5)\" style=filled fillcolor=lightblue];",
+		"n1 [shape=box label=\"1: leaf [5, 10)\" style=filled fillcolor=lightblue];",
+		"n2 [shape=ellipse label=\"2: branch\"];",
+		"n2 -> n0 [label=\"L\"];",
+		"n4 [shape=ellipse label=\"4: branch\"];",
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, dot)
+		}
+	}
+
+	t.Run("options", func(t *testing.T) {
+		sb.Reset()
+		if err := ts.WriteDOTWith(&sb, DOTOptions{Lengths: true, Depths: true}, a, b); err != nil {
+			t.Fatal(err)
+		}
+		dot := sb.String()
+		for _, want := range []string{
+			"label=\"2: branch\\nlen 10\\ndepth 0\"",
+			"label=\"1: leaf [5, 10)\\nlen 5\\ndepth 1\"",
+			"label=\"3: leaf [10, 13)\\nlen 3\\ndepth 2\"",
+		} {
+			if !strings.Contains(dot, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, dot)
+			}
+		}
+	})
+	t.Run("shared within a tree", func(t *testing.T) {
+		ts := NewTreeSlab()
+		shared := ts.addBranch(ts.AddLeaf(0, 1), ts.AddLeaf(1, 1))
+		// the left child reaches shared at depth 2, which is walked before the right child reaches it at depth 1
+		root := ts.addBranch(ts.addBranch(shared, ts.AddLeaf(2, 1)), shared)
+		var sb strings.Builder
+		if err := ts.WriteDOTWith(&sb, DOTOptions{Depths: true}, root); err != nil {
+			t.Fatal(err)
+		}
+		dot := sb.String()
+		for _, want := range []string{
+			"label=\"2: branch\\ndepth 1\"",
+			"label=\"0: leaf [0, 1)\\ndepth 2\"",
+			"label=\"1: leaf [1, 2)\\ndepth 2\"",
+		} {
+			if !strings.Contains(dot, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, dot)
+			}
+		}
+	})
+}
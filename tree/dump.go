@@ -0,0 +1,105 @@
+package tree
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Dump returns a multi-line representation of the (sub)tree rooted at the given node index, with each node on its
+// own line in the format of node.String(), indented by a tab per level below the root, children in order.
+func (ts *TreeSlab) Dump(root uint32) string {
+	var sb strings.Builder
+	ts.Walk(root, func(index uint32, depth int, offset uint32, n *node) WalkAction {
+		sb.WriteString(strings.Repeat("\t", depth))
+		sb.WriteString(n.String())
+		sb.WriteByte('\n')
+		return Continue
+	})
+	return sb.String()
+}
+
+// parseFrame is a branch Parse has read, waiting for its children.
+type parseFrame struct {
+	depth    int
+	children []uint32
+}
+
+// Parse adds the nodes of a tree in the format written by Dump to the TreeSlab, returning the index of its root.
+// The children of a branch are given by the lines indented below it, so the indices in a branch line are ignored,
+// and may be left out entirely, as just "branch". Blank lines are skipped.
+// Nodes are added children first, so parsing a dump into a new TreeSlab gives the indices that it was dumped with,
+// as long as it was built the same way.
+func (ts *TreeSlab) Parse(text string) (uint32, error) {
+	var stack []parseFrame
+	var root uint32
+	done := false
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if done {
+			return 0, fmt.Errorf("line %d: unexpected node after the end of the tree", i+1)
+		}
+		s := strings.TrimLeft(line, "\t")
+		depth := len(line) - len(s)
+		if len(stack) > 0 && depth != stack[len(stack)-1].depth+1 || len(stack) == 0 && depth != 0 {
+			return 0, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+		s = strings.TrimSpace(s)
+		n, err := parseNode(s)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if !n.leaf {
+			stack = append(stack, parseFrame{depth: depth})
+			continue
+		}
+		// add completed nodes to their parents, adding any branches which that completes in turn
+		index := ts.AddLeaf(n.x, n.y)
+		for {
+			if len(stack) == 0 {
+				root, done = index, true
+				break
+			}
+			top := &stack[len(stack)-1]
+			top.children = append(top.children, index)
+			if len(top.children) < 2 {
+				break
+			}
+			if uint64(ts.Len(top.children[0]))+uint64(ts.Len(top.children[1])) > math.MaxUint32 {
+				return 0, fmt.Errorf("line %d: branch: %w", i+1, ErrIndexOverflow)
+			}
+			index = ts.addBranch(top.children[0], top.children[1])
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if !done {
+		return 0, fmt.Errorf("incomplete tree")
+	}
+	return root, nil
+}
+
+// parseNode parses a single node in the format of node.String(), or a bare "branch".
+// The whole of the text must match the format exactly, and a leaf mustn't extend past the last addressable index.
+func parseNode(s string) (node, error) {
+	if s == "branch" {
+		return node{}, nil
+	}
+	var n node
+	var err error
+	if strings.HasPrefix(s, "branch ") {
+		_, err = fmt.Sscanf(s, "branch {left: %d right: %d}", &n.x, &n.y)
+	} else {
+		n.leaf = true
+		_, err = fmt.Sscanf(s, "leaf {index: %d length: %d}", &n.x, &n.y)
+	}
+	// formatting the node again catches anything Sscanf lets through, such as trailing text
+	if err != nil || n.String() != s {
+		return node{}, fmt.Errorf("%q is not a node", s)
+	}
+	if n.leaf && uint64(n.x)+uint64(n.y) > math.MaxUint32 {
+		return node{}, fmt.Errorf("leaf %q: %w", s, ErrIndexOverflow)
+	}
+	return n, nil
+}
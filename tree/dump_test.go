@@ -0,0 +1,75 @@
+package tree
+
+import "testing"
+
+func TestDump(t *testing.T) {
+	ts := NewTreeSlab()
+	root := ts.insert(ts.addBranch(ts.AddLeaf(0, 5), ts.AddLeaf(5, 5)), 7, ts.AddLeaf(10, 10))
+	expected := `branch {left: 0 right: 7}
+	leaf {index: 0 length: 5}
+	branch {left: 4 right: 6}
+		leaf {index: 5 length: 2}
+		branch {left: 3 right: 5}
+			leaf {index: 10 length: 10}
+			leaf {index: 7 length: 3}
+`
+	if d := ts.Dump(root); d != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, d)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		text := `branch {left: 2 right: 3}
+	branch {left: 0 right: 1}
+		leaf {index: 0 length: 5}
+		leaf {index: 5 length: 5}
+	leaf {index: 10 length: 10}
+`
+		ts := NewTreeSlab()
+		root, err := ts.Parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := ts.Dump(root); d != text {
+			t.Errorf("Expected:\n%s\ngot:\n%s", text, d)
+		}
+		if l := ts.Len(root); l != 20 {
+			t.Errorf("Expected length 20, got %d", l)
+		}
+	})
+
+	t.Run("bare branches", func(t *testing.T) {
+		ts := NewTreeSlab()
+		root, err := ts.Parse("branch\n\tleaf {index: 0 length: 5}\n\n\tleaf {index: 9 length: 1}\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves := ts.GetLeaves(root)
+		if len(leaves) != 2 || leaves[0].x != 0 || leaves[1].x != 9 {
+			t.Errorf("Expected leaves 0:5 and 9:1, got %v", leaves)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, text := range map[string]string{
+			"empty":         "",
+			"incomplete":    "branch\n\tleaf {index: 0 length: 5}\n",
+			"indentation":   "branch\n\t\tleaf {index: 0 length: 5}\n",
+			"trailing":      "leaf {index: 0 length: 5}\nleaf {index: 0 length: 5}\n",
+			"garbage":       "twig {index: 0}\n",
+			"long branch":   "branch\n\tleaf {index: 0 length: 4294967295}\n\tleaf {index: 0 length: 1}\n",
+			"overflow":      "leaf {index: 4294967295 length: 2}\n",
+			"branchfoo":     "branchfoo\n\tleaf {index: 0 length: 5}\n\tleaf {index: 5 length: 5}\n",
+			"trailing leaf": "leaf {index: 0 length: 5}x\n",
+			"bad branch":    "branch {left: 0}\n\tleaf {index: 0 length: 5}\n\tleaf {index: 5 length: 5}\n",
+		} {
+			t.Run(name, func(t *testing.T) {
+				ts := NewTreeSlab()
+				if _, err := ts.Parse(text); err == nil {
+					t.Errorf("Expected an error parsing %q", text)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,236 @@
+package tree
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"os"
+	"reflect"
+	"unsafe"
+)
+
+// FILE_SLAB_MAGIC identifies files created by a FileSlab.
+const FILE_SLAB_MAGIC = "SAFS"
+
+// FILE_SLAB_VERSION is the version of the FileSlab file format.
+const FILE_SLAB_VERSION = 1
+
+// FILE_SLAB_HEADER_SIZE is the number of bytes reserved at the start of a FileSlab file for its header.
+// It is large enough that the items which follow it are suitably aligned for any fixed-size type.
+const FILE_SLAB_HEADER_SIZE = 64
+
+// FILE_SLAB_CHUNK_SIZE is the smallest number of bytes a FileSlab grows its backing file by when it is full.
+// Files grow by at least their current size, so the number of times a file is remapped is logarithmic in its size.
+const FILE_SLAB_CHUNK_SIZE = 256 * SLAB_CHUNK_SIZE
+
+// FileSlab is a Slab of fixed-size items stored in a memory mapped file, allowing slabs larger than the
+// available RAM, and slabs which persist between runs of a program.
+// Items are stored in the native memory layout of T, so files are not portable between architectures.
+// Growing the file maps it afresh, but earlier mappings are kept until Close, so references returned by GetRef and
+// Span remain valid, and see the same items, until the slab is closed.
+type FileSlab[T any] struct {
+	file  *os.File
+	data  []byte
+	items []T
+	// retired holds the mappings replaced by growing the file, which may still be referenced.
+	retired [][]byte
+}
+
+// OpenFileSlab opens the FileSlab stored at the given path, creating it if it doesn't exist.
+// T must be a fixed-size type, i.e. one containing no pointers, slices, maps, strings, etc.
+func OpenFileSlab[T any](path string) (*FileSlab[T], error) {
+	var zero T
+	if !isFixedSize(reflect.TypeOf(zero)) {
+		return nil, fmt.Errorf("%T is not a fixed-size type", zero)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileSlab[T]{file: f}
+	if err = s.open(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// open maps the backing file, writing a fresh header if the file is empty, or validating the existing one.
+func (s *FileSlab[T]) open() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if size == 0 {
+		size = FILE_SLAB_CHUNK_SIZE
+		if err = s.file.Truncate(size); err != nil {
+			return err
+		}
+		if err = s.mmap(size); err != nil {
+			return err
+		}
+		copy(s.data, FILE_SLAB_MAGIC)
+		binary.LittleEndian.PutUint32(s.data[4:], FILE_SLAB_VERSION)
+		binary.LittleEndian.PutUint32(s.data[8:], uint32(s.itemSize()))
+		s.setLen(0)
+		return nil
+	}
+	if size < FILE_SLAB_HEADER_SIZE {
+		return fmt.Errorf("file slab is truncated")
+	}
+	if err = s.mmap(size); err != nil {
+		return err
+	}
+	if string(s.data[:4]) != FILE_SLAB_MAGIC {
+		return fmt.Errorf("file is not a file slab")
+	}
+	if v := binary.LittleEndian.Uint32(s.data[4:]); v != FILE_SLAB_VERSION {
+		return fmt.Errorf("unsupported file slab version %d", v)
+	}
+	if is := binary.LittleEndian.Uint32(s.data[8:]); is != uint32(s.itemSize()) {
+		return fmt.Errorf("file slab item size is %d, expected %d", is, s.itemSize())
+	}
+	if uint64(s.Len()) > uint64(cap(s.items)) {
+		return fmt.Errorf("file slab is truncated")
+	}
+	s.items = s.items[:s.Len()]
+	return nil
+}
+
+// mmap maps size bytes of the backing file, retiring any existing mapping rather than unmapping it, as references
+// into it may still be held. Both mappings share the file's pages, so writes through either are seen by both.
+func (s *FileSlab[T]) mmap(size int64) error {
+	data, err := mmap(s.file, size)
+	if err != nil {
+		return err
+	}
+	if s.data != nil {
+		s.retired = append(s.retired, s.data)
+	}
+	s.data = data
+	n := uintptr(size-FILE_SLAB_HEADER_SIZE) / s.itemSize()
+	if n > 0 {
+		s.items = unsafe.Slice((*T)(unsafe.Pointer(&data[FILE_SLAB_HEADER_SIZE])), n)[:0]
+	}
+	return nil
+}
+
+// itemSize returns the size of T in bytes, treating zero-sized types as a single byte so they still occupy
+// distinct slots in the file.
+func (s *FileSlab[T]) itemSize() uintptr {
+	var zero T
+	return max(unsafe.Sizeof(zero), 1)
+}
+
+// setLen records the number of items in the header of the backing file.
+func (s *FileSlab[T]) setLen(n uint32) {
+	binary.LittleEndian.PutUint32(s.data[12:], n)
+}
+
+// grow extends the backing file, in multiples of FILE_SLAB_CHUNK_SIZE, until it can hold at least n items, and at
+// least doubling its size, so the retired mappings never take up more address space than the current one.
+func (s *FileSlab[T]) grow(n uint32) error {
+	need := max(FILE_SLAB_HEADER_SIZE+int64(n)*int64(s.itemSize()), 2*int64(len(s.data)))
+	size := (need + FILE_SLAB_CHUNK_SIZE - 1) / FILE_SLAB_CHUNK_SIZE * FILE_SLAB_CHUNK_SIZE
+	if err := s.file.Truncate(size); err != nil {
+		return err
+	}
+	l := s.Len()
+	if err := s.mmap(size); err != nil {
+		return err
+	}
+	s.items = s.items[:l]
+	return nil
+}
+
+// Add appends items to the end of the slab, growing the backing file if necessary.
+// It panics if the backing file can't be grown, as the Slab interface has no way to report the error.
+func (s *FileSlab[T]) Add(items ...T) (start uint32, length uint32) {
+	start = s.Len()
+	length = checkedAdd(0, uint64(len(items)))
+	if int(checkedAdd(start, uint64(length))) > cap(s.items) {
+		if err := s.grow(start + length); err != nil {
+			panic(fmt.Errorf("growing file slab: %w", err))
+		}
+	}
+	s.items = append(s.items, items...)
+	s.setLen(start + length)
+	return
+}
+
+func (s *FileSlab[T]) Get(index uint32) T {
+	return s.items[index]
+}
+
+func (s *FileSlab[T]) GetRef(index uint32) *T {
+	return &s.items[index]
+}
+
+func (s *FileSlab[T]) Set(index uint32, item T) {
+	s.items[index] = item
+}
+
+func (s *FileSlab[T]) Len() uint32 {
+	return binary.LittleEndian.Uint32(s.data[12:])
+}
+
+func (s *FileSlab[T]) SliceIter(start uint32, length uint32) iter.Seq[T] {
+	return MinimalSlab[T](s.items).SliceIter(start, length)
+}
+
+func (s *FileSlab[T]) Span(start uint32, length uint32) []T {
+	return s.items[start : start+length : start+length]
+}
+
+func (s *FileSlab[T]) SpanIter(start uint32, length uint32) iter.Seq[[]T] {
+	return spanOf(s.Span(start, length))
+}
+
+// Sync flushes the contents of the slab to the backing file.
+func (s *FileSlab[T]) Sync() error {
+	return s.file.Sync()
+}
+
+// Close syncs and unmaps the slab, including any retired mappings, and closes the backing file. The slab, and any
+// references into it, must not be used afterwards.
+func (s *FileSlab[T]) Close() error {
+	err := s.Sync()
+	for _, data := range append(s.retired, s.data) {
+		if data == nil {
+			continue
+		}
+		if e := munmap(data); err == nil {
+			err = e
+		}
+	}
+	s.data, s.items, s.retired = nil, nil, nil
+	if e := s.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// isFixedSize reports whether values of type t can be safely stored as raw bytes, i.e. they don't contain any
+// pointers which would be meaningless once written to a file.
+func isFixedSize(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return isFixedSize(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !isFixedSize(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
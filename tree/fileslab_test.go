@@ -0,0 +1,157 @@
+//go:build unix
+
+package tree
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSlab_Add(t *testing.T) {
+	s, err := OpenFileSlab[int](filepath.Join(t.TempDir(), "slab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	start, length := s.Add(1, 2, 3)
+	if start != 0 {
+		t.Error("Expected start 0, got", start)
+	}
+	if length != 3 {
+		t.Error("Expected length 3, got", length)
+	}
+	if s.Get(1) != 2 {
+		t.Error("Expected 2, got", s.Get(1))
+	}
+	if span := s.Span(1, 2); len(span) != 2 || span[0] != 2 || span[1] != 3 {
+		t.Error("Expected [2 3], got", span)
+	}
+}
+
+func TestFileSlab_Grow(t *testing.T) {
+	s, err := OpenFileSlab[uint64](filepath.Join(t.TempDir(), "slab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	n := uint32(FILE_SLAB_CHUNK_SIZE/8) * 3
+	for i := uint32(0); i < n; i++ {
+		s.Add(uint64(i))
+	}
+	if s.Len() != n {
+		t.Error("Expected", n, "got", s.Len())
+	}
+	for i := uint32(0); i < n; i += 1000 {
+		if s.Get(i) != uint64(i) {
+			t.Error("Expected", i, "got", s.Get(i))
+		}
+	}
+}
+
+func TestFileSlab_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slab")
+	s, err := OpenFileSlab[int32](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(1, 2, 3)
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenFileSlab[int32](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 3 {
+		t.Fatal("Expected 3, got", s.Len())
+	}
+	i := int32(1)
+	for x := range s.SliceIter(0, 3) {
+		if x != i {
+			t.Error("Expected", i, "got", x)
+		}
+		i++
+	}
+
+	if _, err = OpenFileSlab[int64](path); err == nil {
+		t.Error("Expected an item size mismatch error")
+	}
+}
+
+func TestFileSlab_NotFixedSize(t *testing.T) {
+	if _, err := OpenFileSlab[string](filepath.Join(t.TempDir(), "slab")); err == nil {
+		t.Error("Expected an error for a string slab")
+	}
+	if _, err := OpenFileSlab[struct{ p *int }](filepath.Join(t.TempDir(), "slab")); err == nil {
+		t.Error("Expected an error for a pointer slab")
+	}
+}
+
+func TestOpenFileTreeSlab(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree")
+	ts, err := OpenFileTreeSlab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := ts.addBranch(ts.AddLeaf(0, 10), ts.AddLeaf(10, 20))
+	if err = ts.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err = OpenFileTreeSlab(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	if ts.Len(root) != 30 {
+		t.Error("Expected 30, got", ts.Len(root))
+	}
+	leaves := ts.GetLeaves(root)
+	if leaves[1].String() != "leaf {index: 10 length: 20}" {
+		t.Errorf("Expected leaf {index: 10 length: 20}, got %s", leaves[1].String())
+	}
+}
+
+func TestFileSlab_RefAcrossGrow(t *testing.T) {
+	s, err := OpenFileSlab[uint64](filepath.Join(t.TempDir(), "slab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Add(42)
+	ref := s.GetRef(0)
+	n := 3 * cap(s.items)
+	for i := 0; i < n; i++ {
+		s.Add(uint64(i))
+	}
+	if *ref != 42 {
+		t.Error("Expected a reference taken before growing to still read 42, got", *ref)
+	}
+	s.Set(0, 7)
+	if *ref != 7 {
+		t.Error("Expected a reference taken before growing to see later writes, got", *ref)
+	}
+}
+
+func TestFileTreeSlab_RemoveAcrossGrow(t *testing.T) {
+	ts, err := OpenFileTreeSlab(filepath.Join(t.TempDir(), "tree"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	root := ts.addBranch(ts.addBranch(ts.AddLeaf(0, 5), ts.AddLeaf(5, 5)), ts.AddLeaf(10, 5))
+	// fill the node file, so the first node the Remove adds grows it
+	for fs := ts.nodes.(*FileSlab[node]); len(fs.items) < cap(fs.items); {
+		ts.AddLeaf(0, 0)
+	}
+	r := ts.Remove(root, 5, 7)
+	if r == nil {
+		t.Fatal("Expected a tree, got nil")
+	}
+	leaves := ts.GetLeaves(*r)
+	if len(leaves) != 2 || leaves[0].x != 0 || leaves[0].y != 5 || leaves[1].x != 12 || leaves[1].y != 3 {
+		t.Errorf("Expected leaves 0:5 12:3, got %v", leaves)
+	}
+}
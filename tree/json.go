@@ -0,0 +1,146 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// MarshalJSON encodes the SpliceArray as a JSON array of its items, encoding each straight from the data slab rather
+// than collecting them into a slice first. Even a SpliceArray[byte] is encoded as an array of numbers, rather than
+// base64 as a []byte would be. The zero SpliceArray is encoded as an empty array. It implements json.Marshaler.
+func (sa SpliceArray[T]) MarshalJSON() ([]byte, error) {
+	return sa.appendJSON(nil)
+}
+
+// appendJSON appends the items of the SpliceArray to buf as a JSON array.
+func (sa SpliceArray[T]) appendJSON(buf []byte) ([]byte, error) {
+	buf = append(buf, '[')
+	if sa.tree == nil {
+		return append(buf, ']'), nil
+	}
+	first := true
+	for span := range LeafSpans(sa.tree, sa.root, sa.data) {
+		for _, v := range span {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			if !first {
+				buf = append(buf, ',')
+			}
+			first = false
+			buf = append(buf, b...)
+		}
+	}
+	return append(buf, ']'), nil
+}
+
+// MarshalJSONLeaves encodes the SpliceArray as a JSON object of its items, as encoded by MarshalJSON, along with its
+// root and leaves, each as a pair of data slab index and length, for debugging.
+func (sa SpliceArray[T]) MarshalJSONLeaves() ([]byte, error) {
+	buf, err := sa.appendJSON([]byte(`{"items":`))
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, `,"root":`...)
+	buf = strconv.AppendUint(buf, uint64(sa.root), 10)
+	buf = append(buf, `,"leaves":[`...)
+	if sa.tree == nil {
+		return append(buf, "]}"...), nil
+	}
+	first := true
+	for n := range sa.tree.LeafIter(sa.root) {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = fmt.Appendf(buf, "[%d,%d]", n.x, n.y)
+	}
+	return append(buf, "]}"...), nil
+}
+
+// UnmarshalJSON replaces the SpliceArray with a new one, with a new MinimalSlab and TreeSlab, holding the items of a
+// JSON array, which are decoded one at a time straight into the data slab. As is the convention, null leaves the
+// SpliceArray unchanged. It implements json.Unmarshaler.
+func (sa *SpliceArray[T]) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok == nil {
+		return nil
+	} else if tok != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+	data := MinimalSlab[T]{}
+	for dec.More() {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		data.Add(v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	ts := NewTreeSlab()
+	*sa = SpliceArray[T]{data: &data, tree: &ts, root: ts.addRange(0, data.Len())}
+	return nil
+}
+
+// A TextArray is a SpliceArray of bytes or runes which is encoded as UTF-8 text, as a string would be, rather than
+// as an array. It implements encoding.TextMarshaler and encoding.TextUnmarshaler, so it is encoded as a JSON string.
+type TextArray[T byte | rune] struct {
+	sa SpliceArray[T]
+}
+
+// TextArrayOf returns a TextArray of the given SpliceArray.
+func TextArrayOf[T byte | rune](sa SpliceArray[T]) TextArray[T] {
+	return TextArray[T]{sa: sa}
+}
+
+// SpliceArray returns the SpliceArray of the TextArray.
+func (t TextArray[T]) SpliceArray() SpliceArray[T] {
+	return t.sa
+}
+
+// MarshalText encodes the items of the TextArray as UTF-8, a span at a time straight from the data slab. Bytes are
+// copied as they are, and runes are encoded as by utf8.AppendRune. The zero TextArray is empty.
+// It implements encoding.TextMarshaler.
+func (t TextArray[T]) MarshalText() ([]byte, error) {
+	buf := make([]byte, 0, t.sa.Len())
+	if t.sa.tree == nil {
+		return buf, nil
+	}
+	for span := range LeafSpans(t.sa.tree, t.sa.root, t.sa.data) {
+		switch s := any(span).(type) {
+		case []byte:
+			buf = append(buf, s...)
+		case []rune:
+			for _, r := range s {
+				buf = utf8.AppendRune(buf, r)
+			}
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalText replaces the TextArray with a new SpliceArray, with a new MinimalSlab and TreeSlab, holding the
+// bytes or the runes of the text. Text decoded as runes must be valid UTF-8; otherwise an error is returned, and the
+// TextArray is left unchanged. It implements encoding.TextUnmarshaler.
+func (t *TextArray[T]) UnmarshalText(text []byte) error {
+	var items []T
+	switch p := any(&items).(type) {
+	case *[]byte:
+		*p = text
+	case *[]rune:
+		if !utf8.Valid(text) {
+			return fmt.Errorf("text is not valid UTF-8")
+		}
+		*p = []rune(string(text))
+	}
+	t.sa = NewSpliceArray(items...)
+	return nil
+}
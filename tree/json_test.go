@@ -0,0 +1,156 @@
+package tree
+
+import (
+	"encoding"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	sa := NewSpliceArray(1, 2, 3, 4, 5).Insert(2, 9).Remove(4, 1)
+	b, err := json.Marshal(sa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[1,2,9,3,5]" {
+		t.Errorf("Expected [1,2,9,3,5], got %s", b)
+	}
+
+	var decoded SpliceArray[int]
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Collect(decoded.All()); !slices.Equal(got, []int{1, 2, 9, 3, 5}) {
+		t.Errorf("Expected [1 2 9 3 5], got %v", got)
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		type doc struct {
+			Name  string
+			Items SpliceArray[string]
+		}
+		b, err := json.Marshal(doc{"x", NewSpliceArray("a", "b")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d doc
+		if err := json.Unmarshal(b, &d); err != nil {
+			t.Fatal(err)
+		}
+		if got := slices.Collect(d.Items.All()); d.Name != "x" || !slices.Equal(got, []string{"a", "b"}) {
+			t.Errorf("Expected x [a b], got %s %v", d.Name, got)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		b, err := json.Marshal(NewSpliceArray[int]())
+		if err != nil || string(b) != "[]" {
+			t.Errorf("Expected [], got %s %v", b, err)
+		}
+		var decoded SpliceArray[int]
+		if err := json.Unmarshal(b, &decoded); err != nil || decoded.Len() != 0 {
+			t.Errorf("Expected an empty SpliceArray, got %d items %v", decoded.Len(), err)
+		}
+	})
+
+	t.Run("not text", func(t *testing.T) {
+		if _, ok := any(sa).(encoding.TextMarshaler); ok {
+			t.Error("Expected SpliceArray not to claim to be an encoding.TextMarshaler")
+		}
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		var v struct{ S SpliceArray[int] }
+		b, err := json.Marshal(v)
+		if err != nil || string(b) != `{"S":[]}` {
+			t.Errorf("Expected {\"S\":[]}, got %s %v", b, err)
+		}
+		if b, err := v.S.MarshalJSONLeaves(); err != nil || string(b) != `{"items":[],"root":0,"leaves":[]}` {
+			t.Errorf("Unexpected %s %v", b, err)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		v := struct{ S SpliceArray[int] }{NewSpliceArray(1, 2)}
+		if err := json.Unmarshal([]byte(`{"S":null}`), &v); err != nil {
+			t.Fatal(err)
+		}
+		if got := slices.Collect(v.S.All()); !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("Expected null to leave [1 2] unchanged, got %v", got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var decoded SpliceArray[int]
+		for _, s := range []string{`{"a":1}`, `[1,"two"]`, `[1,2`} {
+			if err := json.Unmarshal([]byte(s), &decoded); err == nil {
+				t.Errorf("Expected an error unmarshalling %s", s)
+			}
+		}
+	})
+
+	t.Run("leaves", func(t *testing.T) {
+		b, err := NewSpliceArray(1, 2, 3).Insert(1, 7).MarshalJSONLeaves()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			Items  []int
+			Leaves [][2]uint32
+		}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatalf("%v in %s", err, b)
+		}
+		if !slices.Equal(v.Items, []int{1, 7, 2, 3}) || !slices.Equal(v.Leaves, [][2]uint32{{0, 1}, {3, 1}, {1, 2}}) {
+			t.Errorf("Unexpected %s", b)
+		}
+	})
+}
+
+func TestTextArray(t *testing.T) {
+	text := "héllo, 世界 👋"
+	runesText := TextArrayOf(NewSpliceArray([]rune("héllo")...).Insert(5, []rune(", 世界 👋")...))
+	bytesText := TextArrayOf(NewSpliceArray([]byte("héllo")...).Insert(6, []byte(", 世界 👋")...))
+	for name, m := range map[string]encoding.TextMarshaler{"runes": runesText, "bytes": bytesText} {
+		if b, err := m.MarshalText(); err != nil || string(b) != text {
+			t.Errorf("%s: expected %q, got %q %v", name, text, b, err)
+		}
+	}
+
+	t.Run("json", func(t *testing.T) {
+		b, err := json.Marshal(struct{ R TextArray[rune] }{runesText})
+		if err != nil || string(b) != `{"R":"héllo, 世界 👋"}` {
+			t.Fatalf("Unexpected %s %v", b, err)
+		}
+		var v struct{ R TextArray[rune] }
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(slices.Collect(v.R.SpliceArray().All())); got != text {
+			t.Errorf("Expected %q, got %q", text, got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		r := runesText
+		if err := r.UnmarshalText([]byte("a\xffb")); err == nil {
+			t.Error("Expected an error decoding invalid UTF-8 as runes")
+		}
+		if r.SpliceArray().Len() != runesText.SpliceArray().Len() {
+			t.Error("Expected the TextArray to be unchanged")
+		}
+		// bytes needn't be UTF-8
+		var b TextArray[byte]
+		if err := b.UnmarshalText([]byte("a\xffb")); err != nil || b.SpliceArray().Len() != 3 {
+			t.Errorf("Expected 3 bytes, got %d %v", b.SpliceArray().Len(), err)
+		}
+	})
+
+	t.Run("zero", func(t *testing.T) {
+		var z TextArray[rune]
+		if b, err := z.MarshalText(); err != nil || b == nil || len(b) != 0 {
+			t.Errorf("Expected empty text, got %q %v", b, err)
+		}
+	})
+}
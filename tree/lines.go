@@ -0,0 +1,143 @@
+package tree
+
+import (
+	"bytes"
+	"iter"
+)
+
+// countNewlines returns the number of '\n' bytes in a series of spans.
+func countNewlines(spans iter.Seq[[]byte]) (c uint32) {
+	for span := range spans {
+		c += uint32(bytes.Count(span, []byte{'\n'}))
+	}
+	return
+}
+
+func addNewlines(a, b uint32) uint32 {
+	return a + b
+}
+
+// EnableLineIndex starts maintaining a count of the '\n' bytes in each (sub)tree, counting them in every existing
+// node and then in each node as it is added, so lines can be found by descending the tree with LineStart and LineOf.
+// The leaves of the TreeSlab must all refer to the given data slab, and the data a leaf refers to must be added
+// before the leaf is. Enabling it again with the same data slab does nothing.
+func (ts *TreeSlab) EnableLineIndex(data Slab[byte]) {
+	if ts.lines != nil && ts.lines.data == data {
+		return
+	}
+	ts.lines = newByteMetrics(ts, data, countNewlines, addNewlines)
+}
+
+// Newlines returns the number of '\n' bytes in the (sub)tree rooted at the given node index.
+// It panics if the line index hasn't been enabled.
+func (ts *TreeSlab) Newlines(index uint32) uint32 {
+	return ts.lines.values[index]
+}
+
+// LineCount returns the number of lines in the (sub)tree rooted at the given node index, which is one more than the
+// number of newlines, as the last line needn't end with one.
+// It panics if the line index hasn't been enabled.
+func (ts *TreeSlab) LineCount(index uint32) uint32 {
+	return ts.Newlines(index) + 1
+}
+
+// LineStart returns the position of the first byte of the given zero-based line of the (sub)tree rooted at the
+// given node index, descending by newline counts. It returns false if there is no such line.
+// It takes O(log n) plus a scan of the leaf holding the newline, which is at most BALANCED_LEAF_LENGTH bytes for
+// trees built by SpliceArray, but is as long as the leaf for trees built by hand.
+// It panics if the line index hasn't been enabled.
+func (ts *TreeSlab) LineStart(index, line uint32) (uint32, bool) {
+	if line == 0 {
+		return 0, true
+	}
+	if line > ts.Newlines(index) {
+		return 0, false
+	}
+	// find the newline ending the previous line, which is the line'th newline counting from one
+	var pos uint32
+	for {
+		n := ts.nodes.Get(index)
+		if n.leaf {
+			for span := range ts.lines.data.SpanIter(n.x, n.y) {
+				for i, b := range span {
+					if b != '\n' {
+						continue
+					}
+					if line--; line == 0 {
+						return pos + uint32(i) + 1, true
+					}
+				}
+				pos += uint32(len(span))
+			}
+			panic("line index doesn't match data")
+		}
+		if l := ts.Newlines(n.x); line <= l {
+			index = n.x
+		} else {
+			line -= l
+			pos += ts.Len(n.x)
+			index = n.y
+		}
+	}
+}
+
+// LineOf returns the zero-based line containing the given position of the (sub)tree rooted at the given node index,
+// descending by length. Positions at or past the end are on the last line.
+// It takes O(log n) plus a scan of the leaf holding the position, in the same way as LineStart.
+// It panics if the line index hasn't been enabled.
+func (ts *TreeSlab) LineOf(index, pos uint32) (line uint32) {
+	if pos >= ts.Len(index) {
+		return ts.Newlines(index)
+	}
+	for {
+		n := ts.nodes.Get(index)
+		if n.leaf {
+			for span := range ts.lines.data.SpanIter(n.x, pos) {
+				line += uint32(bytes.Count(span, []byte{'\n'}))
+			}
+			return
+		}
+		if l := ts.Len(n.x); pos < l {
+			index = n.x
+		} else {
+			line += ts.Newlines(n.x)
+			pos -= l
+			index = n.y
+		}
+	}
+}
+
+// EnableLineIndex starts maintaining the line index of the ByteRope's TreeSlab, which LineCount, LineStart and
+// LineOf need, and which ropes edited from this one share. It writes to the TreeSlab, so it must not be called
+// concurrently with any other use of it; once it has been, the line methods are safe to call concurrently.
+func (r *ByteRope) EnableLineIndex() {
+	r.sa.tree.EnableLineIndex(r.sa.data)
+}
+
+// checkLineIndex panics if the line index of the ByteRope's TreeSlab isn't enabled for its data slab.
+func (r *ByteRope) checkLineIndex() {
+	if r.sa.tree == nil || r.sa.tree.lines == nil || r.sa.tree.lines.data != r.sa.data {
+		panic("line index not enabled")
+	}
+}
+
+// LineCount returns the number of lines in the ByteRope.
+// It panics if the line index hasn't been enabled with EnableLineIndex.
+func (r *ByteRope) LineCount() uint32 {
+	r.checkLineIndex()
+	return r.sa.tree.LineCount(r.sa.root)
+}
+
+// LineStart returns the offset of the first byte of the given zero-based line. It returns false if there is no such
+// line. It panics if the line index hasn't been enabled with EnableLineIndex.
+func (r *ByteRope) LineStart(line uint32) (uint32, bool) {
+	r.checkLineIndex()
+	return r.sa.tree.LineStart(r.sa.root, line)
+}
+
+// LineOf returns the zero-based line containing the given offset.
+// It panics if the line index hasn't been enabled with EnableLineIndex.
+func (r *ByteRope) LineOf(offset uint32) uint32 {
+	r.checkLineIndex()
+	return r.sa.tree.LineOf(r.sa.root, offset)
+}
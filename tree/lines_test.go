@@ -0,0 +1,88 @@
+package tree
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	text := "first\nsecond line\n\nfourth\nfifth, without a newline"
+	check := func(t *testing.T, r *ByteRope, text string) {
+		t.Helper()
+		lines := strings.Split(text, "\n")
+		if c := r.LineCount(); c != uint32(len(lines)) {
+			t.Errorf("Expected %d lines, got %d", len(lines), c)
+		}
+		start := uint32(0)
+		for i, l := range lines {
+			if s, ok := r.LineStart(uint32(i)); !ok || s != start {
+				t.Errorf("Expected line %d to start at %d, got %d %v", i, start, s, ok)
+			}
+			for p := start; p <= start+uint32(len(l)) && p < uint32(len(text)); p++ {
+				if got := r.LineOf(p); got != uint32(i) {
+					t.Errorf("Expected offset %d to be on line %d, got %d", p, i, got)
+				}
+			}
+			start += uint32(len(l)) + 1
+		}
+		if _, ok := r.LineStart(uint32(len(lines))); ok {
+			t.Error("Expected no line past the last")
+		}
+		if got := r.LineOf(uint32(len(text))); got != uint32(len(lines)-1) {
+			t.Errorf("Expected the end to be on the last line, got %d", got)
+		}
+	}
+
+	r := fragmentedRope(text)
+	r.EnableLineIndex()
+	check(t, r, text)
+
+	t.Run("edits", func(t *testing.T) {
+		// the index is already enabled, so these nodes are counted as they are added
+		edited := r.Insert(6, []byte("inserted\nlines\n")).Remove(0, 3)
+		check(t, edited, text[3:6]+"inserted\nlines\n"+text[6:])
+		check(t, r, text)
+	})
+
+	t.Run("large insert", func(t *testing.T) {
+		// the inserted text spans several leaves, with a line ending at a leaf boundary
+		big := strings.Repeat(strings.Repeat("x", BALANCED_LEAF_LENGTH-1)+"\n", 3) + "tail"
+		check(t, r.Insert(6, []byte(big)), text[:6]+big+text[6:])
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		r := NewByteRope(nil)
+		r.EnableLineIndex()
+		if c := r.LineCount(); c != 1 {
+			t.Errorf("Expected 1 line, got %d", c)
+		}
+		if got := r.LineOf(0); got != 0 {
+			t.Errorf("Expected line 0, got %d", got)
+		}
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic without the line index")
+			}
+		}()
+		fragmentedRope(text).LineOf(0)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		// reading lines doesn't write to the TreeSlab, so it is safe from many goroutines
+		r := fragmentedRope(text)
+		r.EnableLineIndex()
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				check(t, r, text)
+			}()
+		}
+		wg.Wait()
+	})
+}
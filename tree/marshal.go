@@ -0,0 +1,136 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// TREE_SLAB_MAGIC identifies the binary encoding of a TreeSlab.
+const TREE_SLAB_MAGIC = "SATS"
+
+// TREE_SLAB_FORMAT_VERSION is the version of the binary encoding of a TreeSlab.
+// Decoders must continue to accept every earlier version, so that data written by one release can be read by the next.
+const TREE_SLAB_FORMAT_VERSION = 1
+
+// treeSlabHeaderSize is the size of the encoded header: magic, version, flags and node count.
+const treeSlabHeaderSize = 4 + 2 + 2 + 4
+
+// NODE_RECORD_SIZE is the size of an encoded node: a kind byte, then x and y.
+const NODE_RECORD_SIZE = 1 + 4 + 4
+
+// appendNode appends the little-endian record of a node to buf.
+func appendNode(buf []byte, n node) []byte {
+	kind := byte(0)
+	if n.leaf {
+		kind = 1
+	}
+	buf = append(buf, kind)
+	buf = binary.LittleEndian.AppendUint32(buf, n.x)
+	return binary.LittleEndian.AppendUint32(buf, n.y)
+}
+
+// decodeNode decodes the node record at the start of buf, which must be at least NODE_RECORD_SIZE long.
+// The node is checked to be either a leaf which doesn't extend past the last addressable index, or a branch whose
+// children are earlier nodes than the given index, which guarantees any tree it is part of is acyclic.
+func decodeNode(buf []byte, index uint32) (node, error) {
+	n := node{x: binary.LittleEndian.Uint32(buf[1:]), y: binary.LittleEndian.Uint32(buf[5:])}
+	switch buf[0] {
+	case 0:
+		if n.x >= index || n.y >= index {
+			return n, fmt.Errorf("branch node %d has out of order children %d and %d", index, n.x, n.y)
+		}
+	case 1:
+		n.leaf = true
+		if uint64(n.x)+uint64(n.y) > math.MaxUint32 {
+			return n, fmt.Errorf("leaf node %d: %w", index, ErrIndexOverflow)
+		}
+	default:
+		return n, fmt.Errorf("node %d has invalid kind %d", index, buf[0])
+	}
+	return n, nil
+}
+
+// MarshalBinary encodes every node of the TreeSlab in a stable, versioned, little-endian format: a header of the
+// magic, version, flags and node count, then a record for each node, and finally a CRC-32 checksum of the rest.
+func (ts *TreeSlab) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, ts.binarySize()))
+	_, err := ts.writeBinary(buf)
+	return buf.Bytes(), err
+}
+
+// binarySize returns the size of the encoding written by MarshalBinary.
+func (ts *TreeSlab) binarySize() int64 {
+	return treeSlabHeaderSize + int64(ts.nodes.Len())*NODE_RECORD_SIZE + 4
+}
+
+// writeBinary writes the encoding of MarshalBinary to w, a chunk of nodes at a time, so the whole encoding is never
+// held in memory. It returns the number of bytes written.
+func (ts *TreeSlab) writeBinary(w io.Writer) (int64, error) {
+	crc := crc32.NewIEEE()
+	mw := io.MultiWriter(w, crc)
+	count := ts.nodes.Len()
+	buf := make([]byte, 0, SLAB_CHUNK_SIZE)
+	buf = append(buf, TREE_SLAB_MAGIC...)
+	buf = binary.LittleEndian.AppendUint16(buf, TREE_SLAB_FORMAT_VERSION)
+	buf = binary.LittleEndian.AppendUint16(buf, 0)
+	buf = binary.LittleEndian.AppendUint32(buf, count)
+	var written int64
+	flush := func() error {
+		n, err := mw.Write(buf)
+		written += int64(n)
+		buf = buf[:0]
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		if len(buf)+NODE_RECORD_SIZE > cap(buf) {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+		buf = appendNode(buf, ts.nodes.Get(i))
+	}
+	if err := flush(); err != nil {
+		return written, err
+	}
+	n, err := w.Write(binary.LittleEndian.AppendUint32(nil, crc.Sum32()))
+	return written + int64(n), err
+}
+
+// UnmarshalBinary decodes nodes encoded by MarshalBinary, replacing the nodes of the TreeSlab with a new MinimalSlab
+// holding them. The data is rejected if its checksum doesn't match, or if any branch refers to a child which isn't
+// an earlier node, as every tree built by a TreeSlab is.
+// Any line or text index is dropped, as it no longer matches the nodes.
+func (ts *TreeSlab) UnmarshalBinary(data []byte) error {
+	if len(data) < treeSlabHeaderSize+4 || string(data[:4]) != TREE_SLAB_MAGIC {
+		return fmt.Errorf("data is not an encoded tree slab")
+	}
+	if v := binary.LittleEndian.Uint16(data[4:]); v == 0 || v > TREE_SLAB_FORMAT_VERSION {
+		return fmt.Errorf("unsupported tree slab format version %d", v)
+	}
+	count := binary.LittleEndian.Uint32(data[8:])
+	if uint64(len(data)) != treeSlabHeaderSize+uint64(count)*NODE_RECORD_SIZE+4 {
+		return fmt.Errorf("encoded tree slab of %d nodes has the wrong length %d", count, len(data))
+	}
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return fmt.Errorf("encoded tree slab checksum mismatch")
+	}
+	nodes := make(MinimalSlab[node], 0, max(count, uint32(INITIAL_SLAB_CAPACITY)))
+	for i := uint32(0); i < count; i++ {
+		n, err := decodeNode(body[treeSlabHeaderSize+uint64(i)*NODE_RECORD_SIZE:], i)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, n)
+	}
+	measured := TreeSlab{nodes: &nodes}
+	if err := measured.measure(); err != nil {
+		return err
+	}
+	ts.nodes, ts.lengths, ts.lines, ts.text = &nodes, measured.lengths, nil, nil
+	return nil
+}
@@ -0,0 +1,110 @@
+package tree
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+var _ encoding.BinaryMarshaler = &TreeSlab{}
+var _ encoding.BinaryUnmarshaler = &TreeSlab{}
+
+func TestTreeSlab_MarshalBinary(t *testing.T) {
+	ts, root := generateUnbalancedTree(4, 2, 0)
+	root = *ts.Remove(ts.insert(root, 7, ts.AddLeaf(100, 3)), 2, 9)
+	data, err := ts.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != treeSlabHeaderSize+int(ts.nodes.Len())*NODE_RECORD_SIZE+4 {
+		t.Error("Unexpected encoded length", len(data))
+	}
+
+	var decoded TreeSlab
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.nodes.Len() != ts.nodes.Len() {
+		t.Fatal("Expected", ts.nodes.Len(), "nodes, got", decoded.nodes.Len())
+	}
+	for i := uint32(0); i < ts.nodes.Len(); i++ {
+		if decoded.nodes.Get(i) != ts.nodes.Get(i) {
+			t.Error("Node", i, "expected", ts.nodes.Get(i), "got", decoded.nodes.Get(i))
+		}
+	}
+	var expected []uint32
+	for i := range ts.IndexIter(root) {
+		expected = append(expected, i)
+	}
+	j := 0
+	for i := range decoded.IndexIter(root) {
+		if i != expected[j] {
+			t.Error("Expected", expected[j], "got", i)
+		}
+		j++
+	}
+}
+
+func TestTreeSlab_MarshalBinary_Stable(t *testing.T) {
+	// the encoding of a known tree must never change, so that data written by one release can be read by the next
+	ts := NewTreeSlab()
+	ts.addBranch(ts.AddLeaf(1, 2), ts.AddLeaf(3, 4))
+	data, _ := ts.MarshalBinary()
+	expected := []byte{
+		'S', 'A', 'T', 'S', 1, 0, 0, 0, 3, 0, 0, 0,
+		1, 1, 0, 0, 0, 2, 0, 0, 0,
+		1, 3, 0, 0, 0, 4, 0, 0, 0,
+		0, 0, 0, 0, 0, 1, 0, 0, 0,
+	}
+	expected = binary.LittleEndian.AppendUint32(expected, crc32.ChecksumIEEE(expected))
+	if string(data) != string(expected) {
+		t.Errorf("Expected %v, got %v", expected, data)
+	}
+}
+
+func TestTreeSlab_UnmarshalBinary_Invalid(t *testing.T) {
+	ts := NewTreeSlab()
+	ts.addBranch(ts.AddLeaf(1, 2), ts.AddLeaf(3, 4))
+	valid, _ := ts.MarshalBinary()
+	reseal := func(data []byte) []byte {
+		body := data[:len(data)-4]
+		return binary.LittleEndian.AppendUint32(body, crc32.ChecksumIEEE(body))
+	}
+	corrupt := func(i int, b byte, seal bool) []byte {
+		data := append([]byte{}, valid...)
+		data[i] = b
+		if seal {
+			data = reseal(data)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"magic", corrupt(0, 'X', true)},
+		{"version", corrupt(4, 9, true)},
+		{"truncated", reseal(valid[:len(valid)-5])},
+		{"checksum", corrupt(14, 9, false)},
+		{"kind", corrupt(12, 7, true)},
+		{"out of range child", corrupt(31, 5, true)},
+		{"cycle", corrupt(31, 2, true)},
+		{"overflowing leaf", func() []byte {
+			data := append([]byte{}, valid...)
+			binary.LittleEndian.PutUint32(data[13:], math.MaxUint32)
+			return reseal(data)
+		}()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var decoded TreeSlab
+			if err := decoded.UnmarshalBinary(test.data); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package tree
+
+import "iter"
+
+// byteMetrics is an optional index on a TreeSlab of some measure of the bytes in each (sub)tree, such as the number
+// of newlines, which is kept up to date as nodes are added, so lookups can descend the tree by it.
+type byteMetrics[M any] struct {
+	data Slab[byte]
+	// measure returns the measure of the bytes a leaf refers to, given as a series of spans.
+	measure func(spans iter.Seq[[]byte]) M
+	// add returns the measure of a branch from the measures of its children.
+	add    func(a, b M) M
+	values []M
+}
+
+// newByteMetrics returns a byteMetrics of the leaves of the TreeSlab, which must all refer to the given data slab,
+// having measured every existing node.
+func newByteMetrics[M any](ts *TreeSlab, data Slab[byte], measure func(iter.Seq[[]byte]) M, add func(M, M) M) *byteMetrics[M] {
+	bm := &byteMetrics[M]{data: data, measure: measure, add: add}
+	bm.update(ts)
+	return bm
+}
+
+// update measures every node added to the TreeSlab since it was last updated.
+// Children always have lower indices than their branches, so a single pass in index order is enough. Branches
+// whose children aren't earlier nodes are given the zero measure, in the same way as their lengths.
+func (bm *byteMetrics[M]) update(ts *TreeSlab) {
+	for i := uint32(len(bm.values)); i < ts.nodes.Len(); i++ {
+		n := ts.nodes.Get(i)
+		switch {
+		case n.leaf:
+			bm.values = append(bm.values, bm.measure(bm.data.SpanIter(n.x, n.y)))
+		case n.x < i && n.y < i:
+			bm.values = append(bm.values, bm.add(bm.values[n.x], bm.values[n.y]))
+		default:
+			var zero M
+			bm.values = append(bm.values, zero)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package tree
+
+import (
+	"fmt"
+	"os"
+)
+
+// mmap is not supported on this platform, so FileSlabs can't be opened.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return nil, fmt.Errorf("memory mapped files are not supported on this platform")
+}
+
+// munmap is not supported on this platform.
+func munmap(data []byte) error {
+	return fmt.Errorf("memory mapped files are not supported on this platform")
+}
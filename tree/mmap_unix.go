@@ -0,0 +1,18 @@
+//go:build unix
+
+package tree
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the first size bytes of the given file into memory, shared with the file so writes persist.
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// munmap releases a mapping created by mmap.
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,18 @@
+package tree
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrIndexOverflow is the value slabs and trees panic with when an index or length would no longer fit in a
+// uint32. Sequences which need to grow beyond that should use the 64 bit variants, e.g. TreeSlab64.
+var ErrIndexOverflow = errors.New("index overflows uint32")
+
+// checkedAdd returns a + b, panicking with ErrIndexOverflow if the result doesn't fit in a uint32.
+func checkedAdd(a uint32, b uint64) uint32 {
+	if uint64(a)+b > math.MaxUint32 {
+		panic(ErrIndexOverflow)
+	}
+	return a + uint32(b)
+}
@@ -0,0 +1,85 @@
+package tree
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// PARALLEL_CHUNKS_PER_WORKER is the number of chunks a tree is split into per worker by the parallel helpers, so that
+// workers which finish early can pick up the slack of any which are slowed down.
+const PARALLEL_CHUNKS_PER_WORKER = 4
+
+// parallel splits the positions of the (sub)tree starting at a given node index into roughly equal length chunks,
+// returning the number of chunks, which are numbered in order from zero, and a function which calls f for each of
+// them from a pool of at most workers goroutines, or GOMAXPROCS if workers is not positive, returning once all the
+// calls have.
+func (ts *TreeSlab) parallel(index uint32, workers int) (int, func(f func(chunk int, start, length uint32))) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	total := ts.Len(index)
+	chunks := min(uint32(workers*PARALLEL_CHUNKS_PER_WORKER), total)
+	if chunks == 0 {
+		return 0, func(func(int, uint32, uint32)) {}
+	}
+	size := (total + chunks - 1) / chunks
+	chunks = (total + size - 1) / size
+
+	return int(chunks), func(f func(chunk int, start, length uint32)) {
+		var next atomic.Uint32
+		var wg sync.WaitGroup
+		for w := min(workers, int(chunks)); w > 0; w-- {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for c := next.Add(1) - 1; c < chunks; c = next.Add(1) - 1 {
+					start := c * size
+					f(int(c), start, min(size, total-start))
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+// ParallelForEach calls f with the position and data slab index of every item in the (sub)tree starting at a given
+// node index. The tree is split into roughly equal length chunks which are run on a pool of at most workers
+// goroutines, or GOMAXPROCS if workers is not positive, so f must be safe to call concurrently.
+func (ts *TreeSlab) ParallelForEach(index uint32, workers int, f func(pos, i uint32)) {
+	_, run := ts.parallel(index, workers)
+	run(func(_ int, start, length uint32) {
+		pos := start
+		for n := range ts.RangeIter(index, start, length) {
+			for i := n.x; i < n.x+n.y; i++ {
+				f(pos, i)
+				pos++
+			}
+		}
+	})
+}
+
+// ParallelReduce folds the items of the (sub)tree starting at a given node index in parallel.
+// The tree is split into roughly equal length chunks which are each folded with reduce, starting from zero, on a pool
+// of at most workers goroutines, or GOMAXPROCS if workers is not positive. The results of the chunks are then
+// combined in order, starting from zero again, so combine need only be associative, not commutative, but zero must
+// be an identity of combine, or it will be counted once per chunk.
+func ParallelReduce[A any](ts *TreeSlab, index uint32, workers int, zero A, reduce func(acc A, pos, i uint32) A, combine func(a, b A) A) A {
+	chunks, run := ts.parallel(index, workers)
+	results := make([]A, chunks)
+	run(func(chunk int, start, length uint32) {
+		acc, pos := zero, start
+		for n := range ts.RangeIter(index, start, length) {
+			for i := n.x; i < n.x+n.y; i++ {
+				acc = reduce(acc, pos, i)
+				pos++
+			}
+		}
+		results[chunk] = acc
+	})
+	acc := zero
+	for _, r := range results {
+		acc = combine(acc, r)
+	}
+	return acc
+}
@@ -0,0 +1,82 @@
+package tree
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelForEach(t *testing.T) {
+	ts, idx := generateUnbalancedTree(8, 4, 0)
+	var sum atomic.Uint64
+	var count atomic.Uint32
+	ts.ParallelForEach(idx, 4, func(pos, i uint32) {
+		sum.Add(uint64(i))
+		count.Add(1)
+	})
+	n := uint64(1 << 12)
+	if count.Load() != uint32(n) {
+		t.Error("Expected", n, "items, got", count.Load())
+	}
+	if sum.Load() != n*(n-1)/2 {
+		t.Error("Expected", n*(n-1)/2, "got", sum.Load())
+	}
+}
+
+func TestParallelReduce(t *testing.T) {
+	ts, idx := generateUnbalancedTree(6, 3, 0)
+	var expected []uint32
+	for i := range ts.IndexIter(idx) {
+		expected = append(expected, i)
+	}
+
+	for _, workers := range []int{0, 1, 3, 16} {
+		got := ParallelReduce(&ts, idx, workers, nil,
+			func(acc []uint32, pos, i uint32) []uint32 {
+				if expected[pos] != i {
+					t.Error("Expected", expected[pos], "at", pos, "got", i)
+				}
+				return append(acc, i)
+			},
+			func(a, b []uint32) []uint32 {
+				return append(a, b...)
+			},
+		)
+		if len(got) != len(expected) {
+			t.Fatal("Expected", len(expected), "items, got", len(got))
+		}
+		for j := range got {
+			if got[j] != expected[j] {
+				t.Fatal("Expected results to be combined in order, got", got[j], "at", j)
+			}
+		}
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		ts := NewTreeSlab()
+		// zero is the identity of combine, and is all an empty tree reduces to
+		count := ParallelReduce(&ts, ts.AddLeaf(0, 0), 4, 0, func(acc int, _, _ uint32) int { return acc + 1 },
+			func(a, b int) int { return a + b })
+		if count != 0 {
+			t.Error("Expected 0, got", count)
+		}
+	})
+}
+
+func BenchmarkParallelReduce(b *testing.B) {
+	ts, idx := generateBalancedTree(12, 8)
+	sum := func(acc uint64, _, i uint32) uint64 { return acc + uint64(i) }
+	add := func(a, b uint64) uint64 { return a + b }
+	b.Run("serial", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			acc := uint64(0)
+			for i := range ts.IndexIter(idx) {
+				acc = sum(acc, 0, i)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			ParallelReduce(&ts, idx, 0, 0, sum, add)
+		}
+	})
+}
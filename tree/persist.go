@@ -0,0 +1,282 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SPLICE_ARRAY_MAGIC identifies the encoding of a set of SpliceArrays written by WriteSpliceArrays.
+const SPLICE_ARRAY_MAGIC = "SASA"
+
+// SPLICE_ARRAY_FORMAT_VERSION is the version of the encoding written by WriteSpliceArrays.
+const SPLICE_ARRAY_FORMAT_VERSION = 1
+
+// SPLICE_ARRAY_CHUNK_SIZE is the number of items WriteSpliceArrays encodes at a time, so writing never needs more
+// than a chunk of items in memory at once, however large the data slab.
+const SPLICE_ARRAY_CHUNK_SIZE = SLAB_CHUNK_SIZE
+
+// ElementCodec encodes and decodes the items of a data slab, for saving SpliceArrays.
+type ElementCodec[T any] interface {
+	// Encode returns the encoding of the given items.
+	Encode(items []T) ([]byte, error)
+	// Decode returns the items encoded in data.
+	Decode(data []byte) ([]T, error)
+}
+
+// BinaryCodec is an ElementCodec which encodes items as little-endian binary with encoding/binary, for fixed-size
+// numeric types (and arrays and structs of them) other than int and uint.
+type BinaryCodec[T any] struct{}
+
+func (BinaryCodec[T]) Encode(items []T) ([]byte, error) {
+	return binary.Append(nil, binary.LittleEndian, items)
+}
+
+func (BinaryCodec[T]) Decode(data []byte) ([]T, error) {
+	var zero T
+	size := binary.Size(zero)
+	if size <= 0 {
+		return nil, fmt.Errorf("%T can't be decoded with BinaryCodec", zero)
+	}
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("binary encoded items have a partial item")
+	}
+	items := make([]T, len(data)/size)
+	_, err := binary.Decode(data, binary.LittleEndian, items)
+	return items, err
+}
+
+// GobCodec is an ElementCodec which encodes items with encoding/gob, so it can be used for almost any type.
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(items []T) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(items)
+	return buf.Bytes(), err
+}
+
+func (GobCodec[T]) Decode(data []byte) (items []T, err error) {
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&items)
+	return
+}
+
+// DefaultCodec returns a BinaryCodec if T can be encoded by encoding/binary, and a GobCodec otherwise.
+func DefaultCodec[T any]() ElementCodec[T] {
+	var zero T
+	if binary.Size(zero) > 0 {
+		return BinaryCodec[T]{}
+	}
+	return GobCodec[T]{}
+}
+
+// WriteSpliceArrays writes a set of named SpliceArrays, which must share a data slab and TreeSlab, such as different
+// versions of one sequence. The whole data slab is written, with items encoded by codec a chunk at a time, followed
+// by the whole TreeSlab and the root of each SpliceArray, so the structural sharing between them is preserved.
+// Everything is streamed to w, so the data slab is never copied in memory.
+// It returns the number of bytes written.
+func WriteSpliceArrays[T any](w io.Writer, codec ElementCodec[T], arrays map[string]SpliceArray[T]) (int64, error) {
+	if len(arrays) == 0 {
+		return 0, fmt.Errorf("no splice arrays to write")
+	}
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	first := arrays[names[0]]
+	for _, name := range names {
+		if sa := arrays[name]; sa.data != first.data || sa.tree != first.tree {
+			return 0, fmt.Errorf("splice array %q doesn't share a data slab and tree slab with %q", name, names[0])
+		}
+	}
+
+	cw := &countingWriter{w: w}
+	buf := append([]byte(SPLICE_ARRAY_MAGIC), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(buf[4:], SPLICE_ARRAY_FORMAT_VERSION)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(names)))
+	for _, name := range names {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(name)))
+		buf = append(buf, name...)
+		buf = binary.LittleEndian.AppendUint32(buf, arrays[name].root)
+	}
+	count := first.data.Len()
+	buf = binary.LittleEndian.AppendUint32(buf, count)
+	if _, err := cw.Write(buf); err != nil {
+		return cw.n, err
+	}
+
+	chunk := make([]T, 0, min(count, SPLICE_ARRAY_CHUNK_SIZE))
+	for start := uint32(0); start < count; start += SPLICE_ARRAY_CHUNK_SIZE {
+		chunk = chunk[:0]
+		for span := range first.data.SpanIter(start, min(count-start, SPLICE_ARRAY_CHUNK_SIZE)) {
+			chunk = append(chunk, span...)
+		}
+		encoded, err := codec.Encode(chunk)
+		if err != nil {
+			return cw.n, err
+		}
+		if err = cw.writeBlock(encoded); err != nil {
+			return cw.n, err
+		}
+	}
+
+	if _, err := cw.Write(binary.LittleEndian.AppendUint64(nil, uint64(first.tree.binarySize()))); err != nil {
+		return cw.n, err
+	}
+	_, err := first.tree.writeBinary(cw)
+	return cw.n, err
+}
+
+// ReadSpliceArrays reads a set of named SpliceArrays written by WriteSpliceArrays, decoding items with codec.
+// The SpliceArrays share a new MinimalSlab and TreeSlab, just as the ones written shared theirs.
+// It returns the number of bytes read.
+func ReadSpliceArrays[T any](r io.Reader, codec ElementCodec[T]) (map[string]SpliceArray[T], int64, error) {
+	cr := &countingReader{r: r}
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return nil, cr.n, err
+	}
+	if string(header[:4]) != SPLICE_ARRAY_MAGIC {
+		return nil, cr.n, fmt.Errorf("data is not an encoded splice array")
+	}
+	if v := binary.LittleEndian.Uint16(header[4:]); v == 0 || v > SPLICE_ARRAY_FORMAT_VERSION {
+		return nil, cr.n, fmt.Errorf("unsupported splice array format version %d", v)
+	}
+
+	roots := map[string]uint32{}
+	for i := binary.LittleEndian.Uint32(header[8:]); i > 0; i-- {
+		name, err := cr.readBlock(4)
+		if err != nil {
+			return nil, cr.n, err
+		}
+		root, err := cr.readUint32()
+		if err != nil {
+			return nil, cr.n, err
+		}
+		roots[string(name)] = root
+	}
+
+	count, err := cr.readUint32()
+	if err != nil {
+		return nil, cr.n, err
+	}
+	chunkSize := uint64(SPLICE_ARRAY_CHUNK_SIZE)
+	blocks := (uint64(count) + chunkSize - 1) / chunkSize
+	data := MinimalSlab[T]{}
+	for ; blocks > 0; blocks-- {
+		encoded, err := cr.readBlock(8)
+		if err != nil {
+			return nil, cr.n, err
+		}
+		items, err := codec.Decode(encoded)
+		if err != nil {
+			return nil, cr.n, err
+		}
+		if want := min(uint64(count-data.Len()), chunkSize); uint64(len(items)) != want {
+			return nil, cr.n, fmt.Errorf("expected %d items at %d, decoded %d", want, data.Len(), len(items))
+		}
+		data.Add(items...)
+	}
+
+	nodes, err := cr.readBlock(8)
+	if err != nil {
+		return nil, cr.n, err
+	}
+	ts := NewTreeSlab()
+	if err = ts.UnmarshalBinary(nodes); err != nil {
+		return nil, cr.n, err
+	}
+
+	arrays := make(map[string]SpliceArray[T], len(roots))
+	for name, root := range roots {
+		if root >= ts.nodes.Len() {
+			return nil, cr.n, fmt.Errorf("root %d of %q is out of range", root, name)
+		}
+		for n := range ts.LeafIter(root) {
+			if uint64(n.x)+uint64(n.y) > uint64(data.Len()) {
+				return nil, cr.n, fmt.Errorf("%q refers to items beyond the end of the data slab", name)
+			}
+		}
+		arrays[name] = SpliceArray[T]{data: &data, tree: &ts, root: root}
+	}
+	return arrays, cr.n, nil
+}
+
+// WriteTo writes the SpliceArray, with its whole data slab and TreeSlab, using the DefaultCodec for its items.
+// It implements io.WriterTo.
+func (sa SpliceArray[T]) WriteTo(w io.Writer) (int64, error) {
+	return WriteSpliceArrays(w, DefaultCodec[T](), map[string]SpliceArray[T]{"": sa})
+}
+
+// ReadFrom replaces the SpliceArray with one written by WriteTo, or the only SpliceArray written by
+// WriteSpliceArrays with the DefaultCodec. It implements io.ReaderFrom.
+func (sa *SpliceArray[T]) ReadFrom(r io.Reader) (int64, error) {
+	arrays, n, err := ReadSpliceArrays(r, DefaultCodec[T]())
+	if err != nil {
+		return n, err
+	}
+	if len(arrays) != 1 {
+		return n, fmt.Errorf("expected a single splice array, got %d", len(arrays))
+	}
+	for _, a := range arrays {
+		*sa = a
+	}
+	return n, nil
+}
+
+// countingReader wraps a reader, counting the bytes read from it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (cr *countingReader) readUint32() (uint32, error) {
+	var b [4]byte
+	_, err := io.ReadFull(cr, b[:])
+	return binary.LittleEndian.Uint32(b[:]), err
+}
+
+// readBlock reads a block of bytes prefixed by its length, encoded as a little-endian integer of the given size.
+func (cr *countingReader) readBlock(size int) ([]byte, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(cr, b[:size]); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint64(b[:])
+	// read in bounded chunks, so a corrupt length can't cause a huge allocation up front
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, cr, int64(length)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// countingWriter wraps a writer, counting the bytes written to it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// writeBlock writes a block of bytes prefixed by its length, as a little-endian uint64.
+func (cw *countingWriter) writeBlock(b []byte) error {
+	if _, err := cw.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(b)))); err != nil {
+		return err
+	}
+	_, err := cw.Write(b)
+	return err
+}
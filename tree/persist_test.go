@@ -0,0 +1,140 @@
+package tree
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+)
+
+var _ io.WriterTo = SpliceArray[int]{}
+var _ io.ReaderFrom = &SpliceArray[int]{}
+
+func TestWriteSpliceArrays(t *testing.T) {
+	v1 := NewSpliceArray[int32](1, 2, 3, 4, 5)
+	v2 := v1.Insert(2, 10, 11)
+	v3 := v2.Remove(0, 3)
+	arrays := map[string]SpliceArray[int32]{"v1": v1, "v2": v2, "v3": v3}
+
+	var buf bytes.Buffer
+	written, err := WriteSpliceArrays(&buf, BinaryCodec[int32]{}, arrays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(buf.Len()) {
+		t.Error("Expected", buf.Len(), "bytes written, got", written)
+	}
+
+	read, n, err := ReadSpliceArrays(&buf, BinaryCodec[int32]{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != written {
+		t.Error("Expected", written, "bytes read, got", n)
+	}
+	for name, sa := range arrays {
+		got := slices.Collect(read[name].All())
+		if !slices.Equal(got, slices.Collect(sa.All())) {
+			t.Error(name, "expected", slices.Collect(sa.All()), "got", got)
+		}
+		if read[name].root != sa.root {
+			t.Error(name, "expected root", sa.root, "got", read[name].root)
+		}
+	}
+	if read["v1"].tree != read["v3"].tree || read["v1"].tree.nodes.Len() != v1.tree.nodes.Len() {
+		t.Error("Expected the read splice arrays to share one tree, of the same nodes as the written ones")
+	}
+	if read["v1"].data.Len() != v1.data.Len() {
+		t.Error("Expected the data slab not to be flattened per version")
+	}
+}
+
+func TestWriteSpliceArrays_Unshared(t *testing.T) {
+	arrays := map[string]SpliceArray[int32]{"a": NewSpliceArray[int32](1), "b": NewSpliceArray[int32](2)}
+	if _, err := WriteSpliceArrays(io.Discard, BinaryCodec[int32]{}, arrays); err == nil {
+		t.Error("Expected an error for splice arrays which don't share slabs")
+	}
+}
+
+func TestSpliceArray_WriteTo(t *testing.T) {
+	t.Run("binary", func(t *testing.T) {
+		sa := NewSpliceArray(1.5, 2.5).Insert(1, 9)
+		var buf bytes.Buffer
+		if _, err := sa.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var read SpliceArray[float64]
+		if _, err := read.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := slices.Collect(read.All()); !slices.Equal(got, []float64{1.5, 9, 2.5}) {
+			t.Error("Expected [1.5 9 2.5], got", got)
+		}
+	})
+
+	t.Run("gob", func(t *testing.T) {
+		sa := NewSpliceArray("a", "b", "c").Remove(1, 1)
+		var buf bytes.Buffer
+		if _, err := sa.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var read SpliceArray[string]
+		if _, err := read.ReadFrom(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := slices.Collect(read.All()); !slices.Equal(got, []string{"a", "c"}) {
+			t.Error("Expected [a c], got", got)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		sa := NewSpliceArray[int16](1, 2, 3)
+		var buf bytes.Buffer
+		sa.WriteTo(&buf)
+		data := buf.Bytes()
+		var read SpliceArray[int16]
+		if _, err := read.ReadFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+			t.Error("Expected an error for truncated data")
+		}
+		if _, err := read.ReadFrom(bytes.NewReader(append([]byte("XXXX"), data[4:]...))); err == nil {
+			t.Error("Expected an error for bad magic")
+		}
+	})
+}
+
+// maxWriter records the largest single write made to it.
+type maxWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (w *maxWriter) Write(p []byte) (int, error) {
+	w.max = max(w.max, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestWriteSpliceArrays_Streamed(t *testing.T) {
+	items := make([]uint32, 10*SPLICE_ARRAY_CHUNK_SIZE+7)
+	for i := range items {
+		items[i] = uint32(i)
+	}
+	sa := NewSpliceArray(items...).Remove(5, 3)
+	var w maxWriter
+	n, err := WriteSpliceArrays(&w, DefaultCodec[uint32](), map[string]SpliceArray[uint32]{"": sa})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(w.Len()) {
+		t.Errorf("Expected %d bytes written, got %d", w.Len(), n)
+	}
+	if limit := SPLICE_ARRAY_CHUNK_SIZE * 4; w.max > limit {
+		t.Errorf("Expected no write larger than a chunk of %d bytes, got %d", limit, w.max)
+	}
+	var read SpliceArray[uint32]
+	if _, err := read.ReadFrom(&w); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(slices.Collect(read.All()), slices.Collect(sa.All())) {
+		t.Error("Expected the read SpliceArray to match the written one")
+	}
+}
@@ -0,0 +1,188 @@
+package tree
+
+import (
+	"bufio"
+	"io"
+	"iter"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"unicode/utf8"
+)
+
+// runeWidth returns the width in bytes of the rune at the given offset, counting invalid UTF-8 as one byte per rune,
+// as regexp does.
+func (r *ByteRope) runeWidth(off int64) int64 {
+	var p [utf8.UTFMax]byte
+	n, _ := r.ReadAt(p[:], off)
+	_, w := utf8.DecodeRune(p[:n])
+	return int64(max(w, 1))
+}
+
+// prevRuneWidth returns the width in bytes of the rune before the given offset, in the same way as runeWidth.
+func (r *ByteRope) prevRuneWidth(off int64) int64 {
+	var p [utf8.UTFMax]byte
+	from := max(0, off-utf8.UTFMax)
+	n, _ := r.ReadAt(p[:off-from], from)
+	_, w := utf8.DecodeLastRune(p[:n])
+	return int64(max(w, 1))
+}
+
+// contextRegexps returns the versions of re which regexpMatches runs after the first search, from one rune before
+// where the search starts, so that assertions such as ^, \A and \b have the context of that rune, as the regexp
+// package gives them for a []byte or string. first finds the leftmost match of re after the rune, and if re prefers
+// leftmost-longest matches, longest finds the longest match of re immediately after the rune.
+// The regexp package doesn't record how re was compiled, so it is compared with compilations of its expression to
+// find out, and the expression of a POSIX regexp is rewritten with its flags explicit, to be compiled as Perl syntax.
+func contextRegexps(re *regexp.Regexp) (first, longest *regexp.Regexp) {
+	expr := re.String()
+	isLongest := false
+	if posix, err := regexp.CompilePOSIX(expr); err == nil && reflect.DeepEqual(re, posix) {
+		t, _ := syntax.Parse(expr, syntax.POSIX)
+		expr, isLongest = t.String(), true
+	} else {
+		perl := regexp.MustCompile(expr)
+		perl.Longest()
+		isLongest = reflect.DeepEqual(re, perl)
+	}
+	// the lazy .*? finds the leftmost match of re after the first rune, as an unanchored search would; that is the
+	// start of the leftmost-longest match too, but a longest search would prefer the longest .*?
+	first = regexp.MustCompile(`\A(?s:.)(?s:.*?)(` + expr + `)`)
+	if isLongest {
+		longest = regexp.MustCompile(`\A(?s:.)(` + expr + `)`)
+		longest.Longest()
+	}
+	return
+}
+
+// regexpMatches returns an iterator over the successive non-overlapping matches of re, as submatch index pairs
+// mapped to offsets in the ByteRope, the same as the regexp package's All functions would find in the flattened rope.
+// Each search reads the rope through an io.RuneReader from where the last match ended, so searches after the first
+// run the versions of re from contextRegexps, to give it the context of the rune before.
+// Empty matches abutting a preceding match are ignored, as in the regexp package's All functions.
+func (r *ByteRope) regexpMatches(re *regexp.Regexp) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		first, longest := contextRegexps(re)
+		size := r.Size()
+		// find runs a context regexp from the rune before pos, returning the match of re and its submatches
+		find := func(cre *regexp.Regexp, pos int64) []int {
+			from := pos - r.prevRuneWidth(pos)
+			loc := cre.FindReaderSubmatchIndex(bufio.NewReader(io.NewSectionReader(r, from, size-from)))
+			if loc == nil {
+				return nil
+			}
+			// drop the whole match of the context regexp
+			loc = loc[2:]
+			for i := range loc {
+				if loc[i] >= 0 {
+					loc[i] += int(from)
+				}
+			}
+			return loc
+		}
+		prevEnd := int64(-1)
+		for pos := int64(0); pos <= size; {
+			var loc []int
+			if pos == 0 {
+				loc = re.FindReaderSubmatchIndex(bufio.NewReader(io.NewSectionReader(r, 0, size)))
+			} else if loc = find(first, pos); loc != nil && longest != nil {
+				loc = find(longest, int64(loc[0]))
+			}
+			if loc == nil {
+				return
+			}
+			start, end := int64(loc[0]), int64(loc[1])
+			if start == end && start == prevEnd {
+				if start == size {
+					return
+				}
+				pos = start + r.runeWidth(start)
+				continue
+			}
+			if !yield(loc) {
+				return
+			}
+			prevEnd, pos = end, end
+			if start == end {
+				if end == size {
+					return
+				}
+				pos += r.runeWidth(end)
+			}
+		}
+	}
+}
+
+// FindRegexp returns an iterator over the start and end offsets of the successive non-overlapping matches of re in
+// the ByteRope, which is read through an io.RuneReader rather than being flattened. The matches are the same as
+// re.FindAllIndex would find in the flattened rope.
+func (r *ByteRope) FindRegexp(re *regexp.Regexp) iter.Seq2[uint32, uint32] {
+	return func(yield func(uint32, uint32) bool) {
+		for loc := range r.regexpMatches(re) {
+			if !yield(uint32(loc[0]), uint32(loc[1])) {
+				return
+			}
+		}
+	}
+}
+
+// ReplaceAllRegexp returns a new ByteRope with each match of re, as found by FindRegexp, replaced by repl, in which
+// $ signs are expanded as by regexp.Expand.
+// The replacements are added to the data slab in one go, and the new tree is built in one batch, so there is a single
+// new root no matter how many matches there are, e.g. for a single undo step. The text between matches isn't
+// copied; leaves of the new tree refer to it where it is.
+func (r *ByteRope) ReplaceAllRegexp(re *regexp.Regexp, repl []byte) *ByteRope {
+	type piece struct {
+		replacement bool
+		x, y        uint32
+	}
+	var pieces []piece
+	var buf []byte
+	if r.sa.tree == nil {
+		// the zero ByteRope has no slabs to add the replacements to
+		r = NewByteRope(nil)
+	}
+	ts := r.sa.tree
+	var last uint32
+	keep := func(start, end uint32) {
+		for n := range ts.RangeIter(r.sa.root, start, end-start) {
+			pieces = append(pieces, piece{false, n.x, n.y})
+		}
+	}
+	for loc := range r.regexpMatches(re) {
+		start, end := uint32(loc[0]), uint32(loc[1])
+		keep(last, start)
+		last = end
+		// expand against the text of the match alone, so the whole rope never needs flattening
+		src := make([]byte, end-start)
+		r.ReadAt(src, int64(start))
+		for i := range loc {
+			if loc[i] >= 0 {
+				loc[i] -= int(start)
+			}
+		}
+		l := len(buf)
+		buf = re.Expand(buf, repl, src, loc)
+		pieces = append(pieces, piece{true, uint32(l), uint32(len(buf) - l)})
+	}
+	if pieces == nil {
+		return &ByteRope{sa: r.sa}
+	}
+	keep(last, r.sa.Len())
+
+	base, _ := r.sa.data.Add(buf...)
+	leaves := make([]uint32, 0, len(pieces))
+	for _, p := range pieces {
+		if p.replacement {
+			p.x += base
+		}
+		leaves = ts.appendLeaves(leaves, p.x, p.y)
+	}
+	sa := r.sa
+	if len(leaves) == 0 {
+		sa.root = ts.AddLeaf(0, 0)
+	} else {
+		sa.root = ts.buildBalanced(leaves)
+	}
+	return &ByteRope{sa: sa}
+}
@@ -0,0 +1,139 @@
+package tree
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestFindRegexp(t *testing.T) {
+	text := "the cat sat on the mat, the end"
+	r := fragmentedRope(text)
+	for _, expr := range []string{`[cms]at`, `the`, `t\w*`, `x*`, `,\s`, `q`,
+		`^the`, `\Athe`, `\bt`, `\Bt`, `(?m)^\w`, `\w+$`, `\b`, `^`, `$`} {
+		t.Run(expr, func(t *testing.T) {
+			re := regexp.MustCompile(expr)
+			var got [][]int
+			for s, e := range r.FindRegexp(re) {
+				got = append(got, []int{int(s), int(e)})
+			}
+			want := re.FindAllStringIndex(text, -1)
+			if !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	for _, test := range []struct {
+		name string
+		re   *regexp.Regexp
+		text string
+	}{
+		{"longest", longestRegexp(`a|ab`), "ab ab ab"},
+		{"longest anchored", longestRegexp(`(?m)^(a|ab)`), "ab\nab\nab"},
+		{"posix", regexp.MustCompilePOSIX(`a|ab`), "ab ab ab"},
+		{"posix lines", regexp.MustCompilePOSIX(`^(a|ab)[^b]`), "abc\nabc\nac"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got [][]int
+			for s, e := range fragmentedRope(test.text).FindRegexp(test.re) {
+				got = append(got, []int{int(s), int(e)})
+			}
+			if want := test.re.FindAllStringIndex(test.text, -1); !slices.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	t.Run("utf8", func(t *testing.T) {
+		text := "naïve café, très bien"
+		r := fragmentedRope(text)
+		re := regexp.MustCompile(`\S*[éè]\S*`)
+		var got [][]int
+		for s, e := range r.FindRegexp(re) {
+			got = append(got, []int{int(s), int(e)})
+		}
+		if want := re.FindAllStringIndex(text, -1); !slices.EqualFunc(got, want, slices.Equal) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestReplaceAllRegexp(t *testing.T) {
+	text := "the cat sat on the mat, the end"
+	r := fragmentedRope(text)
+	for _, test := range []struct{ expr, repl string }{
+		{`([cms])at`, `${1}og`},
+		{`the`, `a`},
+		{`t\w*`, ``},
+		{`x*`, `-`},
+		{`.*`, ``},
+		{`q`, `z`},
+		{`^t`, `T`},
+		{`\bt`, `T`},
+		{`\Ba`, `A`},
+		{`\b`, `|`},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			re := regexp.MustCompile(test.expr)
+			nodes := r.sa.tree.nodes.Len()
+			replaced := r.ReplaceAllRegexp(re, []byte(test.repl))
+			if want := re.ReplaceAllString(text, test.repl); replaced.String() != want {
+				t.Errorf("Expected %q, got %q", want, replaced.String())
+			}
+			if r.String() != text {
+				t.Errorf("Expected the original to be unchanged, got %q", r.String())
+			}
+			// every node added belongs to the new tree, under its single new root
+			if added := r.sa.tree.nodes.Len() - nodes; added > 0 && replaced.sa.root != r.sa.tree.nodes.Len()-1 {
+				t.Errorf("Expected the new root to be the last node added")
+			}
+		})
+	}
+
+	t.Run("longest", func(t *testing.T) {
+		for _, test := range []struct {
+			name       string
+			re         *regexp.Regexp
+			text, repl string
+		}{
+			{"longest", longestRegexp(`a|ab`), "ab ab ab", "X"},
+			{"longest submatch", longestRegexp(`(a|ab)(c|bcd)?`), "abcd abcd", "<$1>"},
+			{"posix", regexp.MustCompilePOSIX(`a|ab`), "ab ab ab", "X"},
+			{"posix lines", regexp.MustCompilePOSIX(`^(a|ab)`), "ab\nab\nab", "X"},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				want := test.re.ReplaceAllString(test.text, test.repl)
+				if got := fragmentedRope(test.text).ReplaceAllRegexp(test.re, []byte(test.repl)).String(); got != want {
+					t.Errorf("Expected %q, got %q", want, got)
+				}
+			})
+		}
+	})
+}
+
+// longestRegexp compiles expr and makes it prefer leftmost-longest matches.
+func longestRegexp(expr string) *regexp.Regexp {
+	re := regexp.MustCompile(expr)
+	re.Longest()
+	return re
+}
+
+func TestReplaceAllRegexp_Context(t *testing.T) {
+	for _, test := range []struct{ text, expr, repl string }{
+		{"aaa", `^a`, "b"},
+		{"tt t", `\bt`, "X"},
+		{"one\ntwo\nthree", `(?m)^\w`, "_"},
+		{"aaa", `\Aa|a`, "b"},
+		{"x\xffyy", `\by`, "Y"},
+		{"héé é", `\Bé`, "e"},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			re := regexp.MustCompile(test.expr)
+			want := re.ReplaceAllString(test.text, test.repl)
+			if got := fragmentedRope(test.text).ReplaceAllRegexp(re, []byte(test.repl)).String(); got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+		})
+	}
+}
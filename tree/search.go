@@ -0,0 +1,151 @@
+package tree
+
+import (
+	"iter"
+	"slices"
+)
+
+// kmpTable returns the Knuth-Morris-Pratt failure table of a pattern: the length of the longest proper prefix of
+// each prefix of the pattern which is also a suffix of it.
+func kmpTable[T comparable](pattern []T) []int {
+	table := make([]int, len(pattern))
+	for i, k := 1, 0; i < len(pattern); i++ {
+		for k > 0 && pattern[i] != pattern[k] {
+			k = table[k-1]
+		}
+		if pattern[i] == pattern[k] {
+			k++
+		}
+		table[i] = k
+	}
+	return table
+}
+
+// kmpMatcher matches a pattern against items fed to it one at a time, so it can work through leaf spans without
+// ever needing more than one item of lookbehind.
+type kmpMatcher[T comparable] struct {
+	pattern []T
+	table   []int
+	matched int
+}
+
+func newKMPMatcher[T comparable](pattern []T) *kmpMatcher[T] {
+	return &kmpMatcher[T]{pattern: pattern, table: kmpTable(pattern)}
+}
+
+// next feeds the matcher an item, returning true if it completes a match of the pattern.
+// After a match the matcher starts afresh, so matches never overlap.
+func (m *kmpMatcher[T]) next(v T) bool {
+	for m.matched > 0 && v != m.pattern[m.matched] {
+		m.matched = m.table[m.matched-1]
+	}
+	if v == m.pattern[m.matched] {
+		m.matched++
+	}
+	if m.matched == len(m.pattern) {
+		m.matched = 0
+		return true
+	}
+	return false
+}
+
+// rangeSpans returns an iterator over the items at the positions [start, start+length) of the SpliceArray, as a
+// series of spans.
+func rangeSpans[T any](sa SpliceArray[T], start, length uint32) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		if length == 0 {
+			return
+		}
+		for s, l := range sa.tree.RangeSpanIter(sa.root, start, length) {
+			for span := range sa.data.SpanIter(s, l) {
+				if !yield(span) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Index returns the position of the first occurrence of pattern in sa, or false if there is none.
+func Index[T comparable](sa SpliceArray[T], pattern []T) (uint32, bool) {
+	return IndexRange(sa, pattern, 0, sa.Len())
+}
+
+// IndexRange returns the position of the first occurrence of pattern entirely within the positions
+// [start, start+length) of sa, or false if there is none.
+// An empty pattern is found at start.
+func IndexRange[T comparable](sa SpliceArray[T], pattern []T, start, length uint32) (uint32, bool) {
+	if len(pattern) == 0 {
+		return start, start <= sa.Len()
+	}
+	for pos := range FindAllRange(sa, pattern, start, length) {
+		return pos, true
+	}
+	return 0, false
+}
+
+// FindAll returns an iterator over the positions of the non-overlapping occurrences of pattern in sa, in order.
+// Matches may span any number of leaves; the items are streamed through a KMP matcher span by span, without being
+// copied. An empty pattern is never yielded.
+func FindAll[T comparable](sa SpliceArray[T], pattern []T) iter.Seq[uint32] {
+	return FindAllRange(sa, pattern, 0, sa.Len())
+}
+
+// FindAllRange returns an iterator over the positions of the non-overlapping occurrences of pattern entirely within
+// the positions [start, start+length) of sa, in order, in the same way as FindAll.
+func FindAllRange[T comparable](sa SpliceArray[T], pattern []T, start, length uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		if len(pattern) == 0 {
+			return
+		}
+		m := newKMPMatcher(pattern)
+		pos := start
+		for span := range rangeSpans(sa, start, length) {
+			for _, v := range span {
+				pos++
+				if m.next(v) && !yield(pos-uint32(len(pattern))) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// LastIndex returns the position of the last occurrence of pattern in sa, or false if there is none.
+func LastIndex[T comparable](sa SpliceArray[T], pattern []T) (uint32, bool) {
+	return LastIndexRange(sa, pattern, 0, sa.Len())
+}
+
+// LastIndexRange returns the position of the last occurrence of pattern entirely within the positions
+// [start, start+length) of sa, or false if there is none. The window is searched from right to left, matching the
+// reversed pattern, so only as much of it as precedes the match is read.
+// An empty pattern is found at the end of the window.
+func LastIndexRange[T comparable](sa SpliceArray[T], pattern []T, start, length uint32) (uint32, bool) {
+	// the window may extend past the end, as far as the last addressable index, as in IndexRange
+	end := uint32(min(uint64(start)+uint64(length), uint64(sa.Len())))
+	if len(pattern) == 0 {
+		return end, start <= end
+	}
+	if start >= end {
+		return 0, false
+	}
+	reversed := slices.Clone(pattern)
+	slices.Reverse(reversed)
+	m := newKMPMatcher(reversed)
+	pos := end
+	for n := range sa.tree.ReverseLeafIter(sa.root, end) {
+		spans := slices.Collect(sa.data.SpanIter(n.x, n.y))
+		for i := len(spans) - 1; i >= 0; i-- {
+			for j := len(spans[i]) - 1; j >= 0; j-- {
+				if pos == start {
+					return 0, false
+				}
+				pos--
+				if m.next(spans[i][j]) {
+					return pos, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,95 @@
+package tree
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	text := "abracadabra, abracadabra"
+	r := fragmentedRope(text)
+	sa := r.SpliceArray()
+
+	for _, pattern := range []string{"abra", "a", "cad", "ra, ab", "abracadabra", "x", "abracadabra, abracadabrax"} {
+		t.Run(pattern, func(t *testing.T) {
+			want := strings.Index(text, pattern)
+			if got, ok := Index(sa, []byte(pattern)); ok != (want >= 0) || ok && got != uint32(want) {
+				t.Errorf("Index: expected %d, got %d %v", want, got, ok)
+			}
+			want = strings.LastIndex(text, pattern)
+			if got, ok := LastIndex(sa, []byte(pattern)); ok != (want >= 0) || ok && got != uint32(want) {
+				t.Errorf("LastIndex: expected %d, got %d %v", want, got, ok)
+			}
+			var all []uint32
+			for i := 0; ; {
+				j := strings.Index(text[i:], pattern)
+				if j < 0 {
+					break
+				}
+				all = append(all, uint32(i+j))
+				i += j + len(pattern)
+			}
+			if got := slices.Collect(FindAll(sa, []byte(pattern))); !slices.Equal(got, all) {
+				t.Errorf("FindAll: expected %v, got %v", all, got)
+			}
+		})
+	}
+
+	t.Run("overlapping", func(t *testing.T) {
+		sa := NewSpliceArray(1, 1, 1, 1, 1)
+		if got := slices.Collect(FindAll(sa, []int{1, 1})); !slices.Equal(got, []uint32{0, 2}) {
+			t.Errorf("Expected [0 2], got %v", got)
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		if got, ok := IndexRange(sa, []byte("abra"), 1, 23); !ok || got != 7 {
+			t.Errorf("Expected 7, got %d %v", got, ok)
+		}
+		if _, ok := IndexRange(sa, []byte("abra"), 1, 9); ok {
+			t.Error("Expected no match overlapping the end of the window")
+		}
+		if got, ok := LastIndexRange(sa, []byte("abra"), 0, 23); !ok || got != 13 {
+			t.Errorf("Expected 13, got %d %v", got, ok)
+		}
+		if _, ok := LastIndexRange(sa, []byte("abra"), 15, 8); ok {
+			t.Error("Expected no match overlapping the start of the window")
+		}
+		if got := slices.Collect(FindAllRange(sa, []byte("a"), 3, 5)); !slices.Equal(got, []uint32{3, 5, 7}) {
+			t.Errorf("Expected [3 5 7], got %v", got)
+		}
+		// windows past the end are clamped to it, however far they reach
+		if got, ok := IndexRange(sa, []byte("abra"), 1, math.MaxUint32); !ok || got != 7 {
+			t.Errorf("Expected 7, got %d %v", got, ok)
+		}
+		if got, ok := LastIndexRange(sa, []byte("abra"), 1, math.MaxUint32); !ok || got != 20 {
+			t.Errorf("Expected 20, got %d %v", got, ok)
+		}
+		if got, ok := LastIndexRange(sa, nil, 1, math.MaxUint32); !ok || got != sa.Len() {
+			t.Errorf("Expected %d, got %d %v", sa.Len(), got, ok)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if got, ok := IndexRange(sa, nil, 3, 5); !ok || got != 3 {
+			t.Errorf("Expected 3, got %d %v", got, ok)
+		}
+		if got, ok := LastIndexRange(sa, nil, 3, 5); !ok || got != 8 {
+			t.Errorf("Expected 8, got %d %v", got, ok)
+		}
+		if got := slices.Collect(FindAll(sa, nil)); len(got) != 0 {
+			t.Errorf("Expected no matches, got %v", got)
+		}
+	})
+}
+
+func BenchmarkIndex(b *testing.B) {
+	text := strings.Repeat("abcdefghij", 100_000) + "needle"
+	sa := NewSpliceArray([]byte(text)...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Index(sa, []byte("needle"))
+	}
+}
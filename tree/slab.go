@@ -1,5 +1,7 @@
 package tree
 
+import "iter"
+
 // Slab is the interface for all the slab implementations in this package.
 type Slab[T any] interface {
 	// Add adds items to the slab and returns the start index and number of added items.
@@ -7,11 +9,63 @@ type Slab[T any] interface {
 	// Get returns the item at the given index.
 	Get(index uint32) T
 	// GetRef returns a reference to the item at the given index.
+	// Slabs which pack their items (e.g. BitSlab, CompressedSlab) can't hand out references into their storage, so
+	// they return a reference to a copy instead; Set is the portable way to change an item.
 	GetRef(index uint32) *T
+	// Set replaces the item at the given index.
+	Set(index uint32, item T)
 	// Len returns the total number of items in the slab.
 	Len() uint32
-	// SliceIter returns a channel that iterates over length items from the start index.
-	SliceIter(start uint32, length uint32) chan T
+	// SliceIter returns an iterator over length items from the start index.
+	SliceIter(start uint32, length uint32) iter.Seq[T]
+	// SpanIter returns an iterator over length items from the start index as a series of spans, one per contiguous
+	// run of storage. Slabs which pack their items yield copies rather than views.
+	SpanIter(start uint32, length uint32) iter.Seq[[]T]
+}
+
+// ContiguousSlab is a Slab which keeps all its items in a single contiguous block of memory, so any range of them
+// can be viewed directly as a slice, without copying.
+type ContiguousSlab[T any] interface {
+	Slab[T]
+	// Span returns a view of length items from the start index. The view shares storage with the slab, and is
+	// only valid for as long as references returned by GetRef would be.
+	Span(start uint32, length uint32) []T
+}
+
+// spanOf returns an iterator which yields a single span, for slabs where any range is contiguous.
+func spanOf[T any](span []T) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		yield(span)
+	}
+}
+
+// copySpans returns an iterator which yields copies of length items from the start index of the slab, in spans of up
+// to SLAB_CHUNK_SIZE items, for slabs which have no contiguous storage to give views of.
+func copySpans[T any](s Slab[T], start uint32, length uint32) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		for length > 0 {
+			span := make([]T, min(length, SLAB_CHUNK_SIZE))
+			for i := range span {
+				span[i] = s.Get(start + uint32(i))
+			}
+			if !yield(span) {
+				return
+			}
+			start += uint32(len(span))
+			length -= uint32(len(span))
+		}
+	}
+}
+
+// getIter returns an iterator which yields length items from the start index of the slab one at a time, via Get.
+func getIter[T any](s Slab[T], start uint32, length uint32) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := start; i < start+length; i++ {
+			if !yield(s.Get(i)) {
+				return
+			}
+		}
+	}
 }
 
 // MinimalSlab is the bare minimum implementation of a Slab.
@@ -20,7 +74,8 @@ type MinimalSlab[T any] []T
 
 func (s *MinimalSlab[T]) Add(items ...T) (start uint32, length uint32) {
 	start = uint32(len(*s))
-	length = uint32(len(items))
+	length = checkedAdd(0, uint64(len(items)))
+	checkedAdd(start, uint64(length))
 	*s = append(*s, items...)
 	return
 }
@@ -33,20 +88,29 @@ func (s MinimalSlab[T]) GetRef(index uint32) *T {
 	return &s[index]
 }
 
+func (s MinimalSlab[T]) Set(index uint32, item T) {
+	s[index] = item
+}
+
 func (s MinimalSlab[T]) Len() uint32 {
 	return uint32(len(s))
 }
 
-func (s MinimalSlab[T]) SliceIter(start uint32, end uint32) chan T {
-	// TODO Error handling
-	length := end - start
-	c := make(chan T, 1)
-	go func() {
-		for i := start; i < start+length; i++ {
-			c <- s.Get(i)
+func (s MinimalSlab[T]) Span(start uint32, length uint32) []T {
+	return s[start : start+length : start+length]
+}
+
+func (s MinimalSlab[T]) SpanIter(start uint32, length uint32) iter.Seq[[]T] {
+	return spanOf(s.Span(start, length))
+}
+
+func (s MinimalSlab[T]) SliceIter(start uint32, length uint32) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, x := range s[start : start+length] {
+			if !yield(x) {
+				return
+			}
 		}
-		close(c)
-	}()
-	return c
+	}
 }
 
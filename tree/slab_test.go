@@ -46,3 +46,66 @@ func TestMinimalSlab_SliceIter(t *testing.T) {
 		i++
 	}
 }
+
+func TestMinimalSlab_Set(t *testing.T) {
+	s := MinimalSlab[int]{}
+	s.Add(1, 2, 3)
+	s.Set(1, 5)
+	if s.Get(1) != 5 {
+		t.Error("Expected 5, got", s.Get(1))
+	}
+}
+
+func TestMinimalSlab_Span(t *testing.T) {
+	s := MinimalSlab[int]{}
+	s.Add(1, 2, 3, 4)
+	span := s.Span(1, 2)
+	if len(span) != 2 || span[0] != 2 || span[1] != 3 {
+		t.Error("Expected [2 3], got", span)
+	}
+	span = append(span, 5)
+	if s.Get(3) != 4 {
+		t.Error("Expected appending to a span not to overwrite the slab")
+	}
+}
+
+// checkSpans checks that the spans from SpanIter cover exactly the items [start, start+length) of a slab.
+func checkSpans[T comparable](t *testing.T, s Slab[T], start, length uint32) {
+	t.Helper()
+	i := start
+	for span := range s.SpanIter(start, length) {
+		for _, x := range span {
+			if x != s.Get(i) {
+				t.Error("Expected", s.Get(i), "got", x, "at", i)
+			}
+			i++
+		}
+	}
+	if i != start+length {
+		t.Error("Expected spans to end at", start+length, "ended at", i)
+	}
+}
+
+func TestSpanIter(t *testing.T) {
+	ms := MinimalSlab[uint16]{}
+	cs := NewConcurrentSlab[uint16]()
+	zs := NewCompressedSlab[uint16]()
+	ps, _ := NewPackedSlab[uint16](10)
+	for i := uint16(0); i < 5000; i++ {
+		ms.Add(i)
+		cs.Add(i)
+		zs.Add(i)
+		ps.Add(i)
+	}
+	bs := &BitSlab{}
+	for i := 0; i < 5000; i++ {
+		bs.Add(i%7 == 0)
+	}
+	for _, r := range [][2]uint32{{0, 5000}, {0, 0}, {63, 2}, {100, 4500}} {
+		checkSpans[uint16](t, &ms, r[0], r[1])
+		checkSpans[uint16](t, cs, r[0], r[1])
+		checkSpans[uint16](t, zs, r[0], r[1])
+		checkSpans[uint16](t, ps, r[0], r[1])
+		checkSpans[bool](t, bs, r[0], r[1])
+	}
+}
@@ -0,0 +1,212 @@
+package tree
+
+import (
+	"fmt"
+	"iter"
+)
+
+// BALANCED_LEAF_LENGTH is the maximum number of items in each leaf of the balanced trees built for new sequences.
+const BALANCED_LEAF_LENGTH = 1024
+
+// A SpliceArray is a sequence of items, stored as a tree of leaves which refer to ranges of a data slab.
+// SpliceArrays are persistent; edits return a new SpliceArray which shares the data slab, TreeSlab, and every
+// unchanged node with the original, which remains valid and unchanged.
+// An empty SpliceArray is represented by a root leaf of length zero. The zero SpliceArray is also empty, and has no
+// data slab or TreeSlab until items are inserted into it.
+type SpliceArray[T any] struct {
+	data Slab[T]
+	tree *TreeSlab
+	root uint32
+}
+
+// NewSpliceArray creates a new SpliceArray holding the given items, with a new MinimalSlab and TreeSlab.
+func NewSpliceArray[T any](items ...T) SpliceArray[T] {
+	data := make(MinimalSlab[T], 0, len(items))
+	ts := NewTreeSlab()
+	return NewSpliceArrayWith[T](&data, &ts, items...)
+}
+
+// NewSpliceArrayWith creates a new SpliceArray holding the given items, which are added to the given data slab, with
+// a balanced tree of nodes added to the given TreeSlab.
+func NewSpliceArrayWith[T any](data Slab[T], ts *TreeSlab, items ...T) SpliceArray[T] {
+	start, length := data.Add(items...)
+	return SpliceArray[T]{data: data, tree: ts, root: ts.addRange(start, length)}
+}
+
+// addRange adds a balanced tree of leaves covering length items of the data slab from the start index, returning the
+// index of its root.
+func (ts *TreeSlab) addRange(start, length uint32) uint32 {
+	if length == 0 {
+		return ts.AddLeaf(start, 0)
+	}
+	return ts.buildBalanced(ts.appendLeaves(make([]uint32, 0, length/BALANCED_LEAF_LENGTH+1), start, length))
+}
+
+// appendLeaves adds leaves of at most BALANCED_LEAF_LENGTH items covering length items of the data slab from the
+// start index, appending their indices to leaves, so that scanning any one leaf stays cheap.
+func (ts *TreeSlab) appendLeaves(leaves []uint32, start, length uint32) []uint32 {
+	for length > 0 {
+		l := min(length, BALANCED_LEAF_LENGTH)
+		leaves = append(leaves, ts.AddLeaf(start, l))
+		start, length = start+l, length-l
+	}
+	return leaves
+}
+
+// Data returns the data slab of the SpliceArray.
+func (sa SpliceArray[T]) Data() Slab[T] {
+	return sa.data
+}
+
+// Tree returns the TreeSlab of the SpliceArray.
+func (sa SpliceArray[T]) Tree() *TreeSlab {
+	return sa.tree
+}
+
+// Root returns the node index of the root of the SpliceArray's tree.
+func (sa SpliceArray[T]) Root() uint32 {
+	return sa.root
+}
+
+// Len returns the number of items in the SpliceArray.
+func (sa SpliceArray[T]) Len() uint32 {
+	if sa.tree == nil {
+		return 0
+	}
+	return sa.tree.Len(sa.root)
+}
+
+// Get returns the item at the given position. It panics if the position is out of range.
+func (sa SpliceArray[T]) Get(pos uint32) T {
+	if pos >= sa.Len() {
+		panic("position out of range")
+	}
+	for n := range sa.tree.RangeIter(sa.root, pos, 1) {
+		return sa.data.Get(n.x)
+	}
+	panic("position out of range")
+}
+
+// Insert returns a new SpliceArray with the given items inserted at the given position.
+// The items are inserted as a balanced tree of leaves of at most BALANCED_LEAF_LENGTH items.
+// It panics if the position is past the end. Inserting into the zero SpliceArray gives it a new MinimalSlab and
+// TreeSlab, as NewSpliceArray does.
+func (sa SpliceArray[T]) Insert(pos uint32, items ...T) SpliceArray[T] {
+	if l := sa.Len(); pos > l {
+		panic(fmt.Sprintf("insert position %d out of range of length %d", pos, l))
+	}
+	if len(items) == 0 {
+		return sa
+	}
+	if sa.tree == nil {
+		return NewSpliceArray(items...)
+	}
+	start, length := sa.data.Add(items...)
+	if sa.Len() == 0 {
+		sa.root = sa.tree.addRange(start, length)
+		return sa
+	}
+	sa.root = sa.tree.insert(sa.root, pos, sa.tree.addRange(start, length))
+	return sa
+}
+
+// Remove returns a new SpliceArray with length items removed from the given position.
+// It panics if the range extends past the end.
+func (sa SpliceArray[T]) Remove(start, length uint32) SpliceArray[T] {
+	if l := sa.Len(); uint64(start)+uint64(length) > uint64(l) {
+		panic(fmt.Sprintf("remove range [%d, %d) out of range of length %d", start, uint64(start)+uint64(length), l))
+	}
+	if length == 0 {
+		return sa
+	}
+	if root := sa.tree.Remove(sa.root, start, length); root != nil {
+		sa.root = *root
+	} else {
+		sa.root = sa.tree.AddLeaf(0, 0)
+	}
+	return sa
+}
+
+// All returns an iterator over the items of the SpliceArray.
+func (sa SpliceArray[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if sa.tree == nil {
+			return
+		}
+		for span := range LeafSpans(sa.tree, sa.root, sa.data) {
+			for _, v := range span {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Map returns a new SpliceArray of the results of calling f on each item of sa, in order.
+// The results are stored in a new data slab, with a new balanced tree, built in a single pass.
+func Map[T, U any](sa SpliceArray[T], f func(T) U) SpliceArray[U] {
+	data := make(MinimalSlab[U], 0, sa.Len())
+	for v := range sa.All() {
+		data = append(data, f(v))
+	}
+	ts := NewTreeSlab()
+	return SpliceArray[U]{data: &data, tree: &ts, root: ts.addRange(0, data.Len())}
+}
+
+// Filter returns a new SpliceArray of the items of sa for which pred returns true, in order.
+// Each run of kept items becomes leaves of at most BALANCED_LEAF_LENGTH items referring to the original data slab, so
+// no items are copied, and the new SpliceArray shares its data slab and TreeSlab with sa.
+func Filter[T any](sa SpliceArray[T], pred func(T) bool) SpliceArray[T] {
+	if sa.Len() == 0 {
+		return sa
+	}
+	var leaves []uint32
+	var run node
+	flush := func() {
+		leaves = sa.tree.appendLeaves(leaves, run.x, run.y)
+	}
+	for n := range sa.tree.LeafIter(sa.root) {
+		for i := n.x; i < n.x+n.y; i++ {
+			if !pred(sa.data.Get(i)) {
+				continue
+			}
+			// extend the current run if it is contiguous in the data slab, even across leaves
+			if run.y > 0 && run.x+run.y == i {
+				run.y++
+				continue
+			}
+			flush()
+			run = node{leaf: true, x: i, y: 1}
+		}
+	}
+	flush()
+	if len(leaves) == 0 {
+		sa.root = sa.tree.AddLeaf(0, 0)
+		return sa
+	}
+	sa.root = sa.tree.buildBalanced(leaves)
+	return sa
+}
+
+// Fold combines the items of sa, in order, with f, starting from init.
+func Fold[T, A any](sa SpliceArray[T], init A, f func(A, T) A) A {
+	acc := init
+	for v := range sa.All() {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Reduce combines the items of sa, in order, with f, starting from the first item.
+// It returns false if sa is empty.
+func Reduce[T any](sa SpliceArray[T], f func(T, T) T) (acc T, ok bool) {
+	for v := range sa.All() {
+		if !ok {
+			acc, ok = v, true
+			continue
+		}
+		acc = f(acc, v)
+	}
+	return
+}
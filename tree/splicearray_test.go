@@ -0,0 +1,177 @@
+package tree
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSpliceArray(t *testing.T) {
+	sa := NewSpliceArray([]rune("hello world")...)
+	if sa.Len() != 11 {
+		t.Error("Expected 11, got", sa.Len())
+	}
+	if sa.Get(4) != 'o' {
+		t.Errorf("Expected 'o', got %q", sa.Get(4))
+	}
+
+	edited := sa.Insert(5, []rune(", cruel")...).Remove(0, 1).Insert(0, 'j')
+	if s := string(slices.Collect(edited.All())); s != "jello, cruel world" {
+		t.Errorf("Expected \"jello, cruel world\", got %q", s)
+	}
+	if s := string(slices.Collect(sa.All())); s != "hello world" {
+		t.Errorf("Expected the original to be unchanged, got %q", s)
+	}
+
+	empty := edited.Remove(0, edited.Len())
+	if empty.Len() != 0 || len(slices.Collect(empty.All())) != 0 {
+		t.Error("Expected an empty SpliceArray")
+	}
+	if s := string(slices.Collect(empty.Insert(0, 'x').All())); s != "x" {
+		t.Errorf("Expected \"x\", got %q", s)
+	}
+}
+
+func TestSpliceArray_Zero(t *testing.T) {
+	var sa SpliceArray[int]
+	if sa.Len() != 0 || len(slices.Collect(sa.All())) != 0 {
+		t.Error("Expected the zero SpliceArray to be empty")
+	}
+	if f := Filter(sa, func(int) bool { return true }); f.Len() != 0 {
+		t.Error("Expected an empty SpliceArray, got", f.Len())
+	}
+	if _, ok := Index(sa, []int{1}); ok {
+		t.Error("Expected nothing to be found")
+	}
+	if _, ok := LastIndex(sa, []int{1}); ok {
+		t.Error("Expected nothing to be found")
+	}
+	if s := slices.Collect(sa.Insert(0, 1, 2).All()); !slices.Equal(s, []int{1, 2}) {
+		t.Error("Expected [1 2], got", s)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Get to panic")
+		}
+	}()
+	sa.Get(0)
+}
+
+func TestSpliceArray_OutOfRange(t *testing.T) {
+	sa := NewSpliceArray(1, 2, 3)
+	expectPanic := func(t *testing.T, want string, f func()) {
+		t.Helper()
+		defer func() {
+			if got, _ := recover().(string); got != want {
+				t.Errorf("Expected panic %q, got %q", want, got)
+			}
+		}()
+		f()
+	}
+	expectPanic(t, "insert position 10 out of range of length 3", func() { sa.Insert(10, 4) })
+	expectPanic(t, "insert position 4 out of range of length 3", func() { sa.Insert(4) })
+	expectPanic(t, "remove range [2, 4) out of range of length 3", func() { sa.Remove(2, 2) })
+	expectPanic(t, "remove range [4, 4) out of range of length 3", func() { sa.Remove(4, 0) })
+	expectPanic(t, "remove range [1, 4294967296) out of range of length 3", func() { sa.Remove(1, math.MaxUint32) })
+
+	// the ends themselves are in range
+	if s := slices.Collect(sa.Insert(3, 4).Remove(0, 4).All()); len(s) != 0 {
+		t.Error("Expected an empty SpliceArray, got", s)
+	}
+}
+
+func TestNewSpliceArray_Balanced(t *testing.T) {
+	items := make([]int, BALANCED_LEAF_LENGTH*5+3)
+	for i := range items {
+		items[i] = i
+	}
+	sa := NewSpliceArray(items...)
+	if !slices.Equal(slices.Collect(sa.All()), items) {
+		t.Error("Expected the SpliceArray to hold the items in order")
+	}
+	leaves := sa.tree.GetLeaves(sa.root)
+	if len(leaves) != 6 {
+		t.Error("Expected 6 leaves, got", len(leaves))
+	}
+}
+
+func TestSpliceArray_LeafLength(t *testing.T) {
+	items := make([]int, BALANCED_LEAF_LENGTH*3+5)
+	for i := range items {
+		items[i] = i
+	}
+	check := func(t *testing.T, sa SpliceArray[int], want []int) {
+		t.Helper()
+		if !slices.Equal(slices.Collect(sa.All()), want) {
+			t.Error("Expected the SpliceArray to hold the items in order")
+		}
+		for _, n := range sa.tree.GetLeaves(sa.root) {
+			if n.y > BALANCED_LEAF_LENGTH {
+				t.Errorf("Expected leaves of at most %d items, got %d", BALANCED_LEAF_LENGTH, n.y)
+			}
+		}
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		sa := NewSpliceArray(-1, -2).Insert(1, items...)
+		check(t, sa, slices.Concat([]int{-1}, items, []int{-2}))
+	})
+	t.Run("insert empty", func(t *testing.T) {
+		check(t, NewSpliceArray[int]().Insert(0, items...), items)
+	})
+	t.Run("filter", func(t *testing.T) {
+		// every item is kept, so the runs are contiguous across all the leaves
+		check(t, Filter(NewSpliceArray(items...), func(int) bool { return true }), items)
+	})
+}
+
+func TestMap(t *testing.T) {
+	sa := NewSpliceArray(1, 2, 3).Insert(1, 10, 20)
+	m := Map(sa, func(v int) string { return string(rune('a' + v)) })
+	if s := slices.Collect(m.All()); !slices.Equal(s, []string{"b", "k", "u", "c", "d"}) {
+		t.Error("Expected [b k u c d], got", s)
+	}
+	if m.Data().Len() != 5 {
+		t.Error("Expected a new data slab of 5 items, got", m.Data().Len())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	sa := NewSpliceArray(items...).Remove(10, 5)
+	dataLen := sa.Data().Len()
+	f := Filter(sa, func(v int) bool { return v < 20 || v%10 == 0 })
+	expected := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 15, 16, 17, 18, 19, 20, 30, 40, 50, 60, 70, 80, 90}
+	if s := slices.Collect(f.All()); !slices.Equal(s, expected) {
+		t.Error("Expected", expected, "got", s)
+	}
+	if f.Data().Len() != dataLen {
+		t.Error("Expected Filter not to copy any items")
+	}
+	leaves := f.tree.GetLeaves(f.root)
+	if len(leaves) != 9 || leaves[0].String() != "leaf {index: 0 length: 10}" || leaves[1].String() != "leaf {index: 15 length: 6}" {
+		t.Error("Expected kept runs to be preserved as leaves, got", leaves)
+	}
+	if Filter(sa, func(int) bool { return false }).Len() != 0 {
+		t.Error("Expected an empty SpliceArray")
+	}
+}
+
+func TestFoldReduce(t *testing.T) {
+	sa := NewSpliceArray(1, 2, 3, 4).Insert(2, 5)
+	if sum := Fold(sa, 100, func(acc, v int) int { return acc + v }); sum != 115 {
+		t.Error("Expected 115, got", sum)
+	}
+	if s := Fold(sa, "", func(acc string, v int) string { return acc + string(rune('0'+v)) }); s != "12534" {
+		t.Error("Expected 12534, got", s)
+	}
+	if max, ok := Reduce(sa, func(a, b int) int { return max(a, b) }); !ok || max != 5 {
+		t.Error("Expected 5, got", max)
+	}
+	if _, ok := Reduce(NewSpliceArray[int](), func(a, b int) int { return a }); ok {
+		t.Error("Expected Reduce of an empty SpliceArray to fail")
+	}
+}
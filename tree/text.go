@@ -0,0 +1,244 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"unicode/utf8"
+)
+
+// ErrInvalidSplit is returned when an offset or edit would split a multi-byte UTF-8 sequence, or a UTF-16 surrogate
+// pair.
+var ErrInvalidSplit = errors.New("offset splits a multi-byte sequence")
+
+// textMetrics are the lengths of some text in each of the coordinate systems a TextRope converts between.
+type textMetrics struct {
+	bytes, runes, utf16 uint32
+}
+
+func (m textMetrics) add(o textMetrics) textMetrics {
+	return textMetrics{m.bytes + o.bytes, m.runes + o.runes, m.utf16 + o.utf16}
+}
+
+// consume adds a byte to the metrics, counting a rune for each byte which starts one, and a second UTF-16 unit for
+// each which starts a four byte sequence, so text can be measured a byte at a time, or a span at a time, whether or
+// not the span boundaries fall between runes.
+func (m *textMetrics) consume(b byte) {
+	m.bytes++
+	if utf8.RuneStart(b) {
+		m.runes++
+		m.utf16++
+		if b >= 0xf0 {
+			m.utf16++
+		}
+	}
+}
+
+// runeCounts are the number of runes and UTF-16 code units in some text, which together with its length in bytes
+// make up its textMetrics.
+type runeCounts struct {
+	runes, utf16 uint32
+}
+
+// countRunes returns the runeCounts of a series of spans.
+func countRunes(spans iter.Seq[[]byte]) runeCounts {
+	var m textMetrics
+	for span := range spans {
+		for _, b := range span {
+			m.consume(b)
+		}
+	}
+	return runeCounts{m.runes, m.utf16}
+}
+
+func addRuneCounts(a, b runeCounts) runeCounts {
+	return runeCounts{a.runes + b.runes, a.utf16 + b.utf16}
+}
+
+// EnableTextIndex starts maintaining the rune and UTF-16 lengths of each (sub)tree, in the same way as
+// EnableLineIndex, so offsets can be converted between bytes, runes and UTF-16 code units by descending the tree.
+func (ts *TreeSlab) EnableTextIndex(data Slab[byte]) {
+	if ts.text != nil && ts.text.data == data {
+		return
+	}
+	ts.text = newByteMetrics(ts, data, countRunes, addRuneCounts)
+}
+
+// textMetrics returns the lengths of the (sub)tree rooted at the given node index.
+// It panics if the text index hasn't been enabled.
+func (ts *TreeSlab) textMetrics(index uint32) textMetrics {
+	c := ts.text.values[index]
+	return textMetrics{ts.Len(index), c.runes, c.utf16}
+}
+
+// textSeek finds the point in the (sub)tree rooted at the given node index where the coordinate selected by of
+// reaches target, descending by that coordinate, and returns the metrics of the text before it.
+// It takes O(log n) plus a scan of the leaf holding the point, which is at most BALANCED_LEAF_LENGTH bytes for trees
+// built by SpliceArray.
+// It returns ErrInvalidSplit if the point would fall inside a rune or surrogate pair.
+func (ts *TreeSlab) textSeek(index, target uint32, of func(textMetrics) uint32) (textMetrics, error) {
+	total := ts.textMetrics(index)
+	if target >= of(total) {
+		if target > of(total) {
+			return textMetrics{}, fmt.Errorf("offset %d is past the end %d", target, of(total))
+		}
+		return total, nil
+	}
+	var acc textMetrics
+	for {
+		n := ts.nodes.Get(index)
+		if n.leaf {
+			for span := range ts.text.data.SpanIter(n.x, n.y) {
+				for _, b := range span {
+					// only the starts of runes are valid points, so a target passed over is inside a rune
+					if utf8.RuneStart(b) && of(acc) >= target {
+						if of(acc) > target {
+							return textMetrics{}, ErrInvalidSplit
+						}
+						return acc, nil
+					}
+					acc.consume(b)
+				}
+			}
+			// a target in this leaf which wasn't found is inside its last rune, which may continue into the next leaf, as
+			// leaves are split by length without regard to runes
+			return textMetrics{}, ErrInvalidSplit
+		}
+		if l := ts.textMetrics(n.x); target-of(acc) < of(l) {
+			index = n.x
+		} else {
+			acc = acc.add(l)
+			index = n.y
+		}
+	}
+}
+
+func byteMetric(m textMetrics) uint32  { return m.bytes }
+func runeMetric(m textMetrics) uint32  { return m.runes }
+func utf16Metric(m textMetrics) uint32 { return m.utf16 }
+
+// A TextRope is a rope of valid UTF-8 text, which tracks the rune and UTF-16 lengths of each (sub)tree along with
+// the byte lengths, so offsets in any of them can be converted to the others in O(log n), as its leaves are at most
+// BALANCED_LEAF_LENGTH bytes, as editors and language servers need. Offsets are in bytes unless stated otherwise.
+// Edits which would split a multi-byte sequence, or insert invalid UTF-8, are rejected with an error, so the text is
+// always valid.
+type TextRope struct {
+	sa SpliceArray[byte]
+}
+
+// NewTextRope creates a new TextRope holding the given text, with a new MinimalSlab and TreeSlab.
+// It returns an error if the text isn't valid UTF-8.
+func NewTextRope(text string) (*TextRope, error) {
+	if !utf8.ValidString(text) {
+		return nil, fmt.Errorf("text is not valid UTF-8")
+	}
+	sa := NewSpliceArray([]byte(text)...)
+	sa.tree.EnableTextIndex(sa.data)
+	return &TextRope{sa: sa}, nil
+}
+
+// ByteRope returns a ByteRope over the bytes of the TextRope.
+func (t *TextRope) ByteRope() *ByteRope {
+	return ByteRopeOf(t.sa)
+}
+
+// String returns the text of the TextRope.
+func (t *TextRope) String() string {
+	return t.ByteRope().String()
+}
+
+// MarshalText returns the text of the TextRope. The zero TextRope is empty.
+// It implements encoding.TextMarshaler.
+func (t *TextRope) MarshalText() ([]byte, error) {
+	if t.sa.tree == nil {
+		return []byte{}, nil
+	}
+	return t.ByteRope().Bytes(), nil
+}
+
+// UnmarshalText replaces the TextRope with a new one holding the given text.
+// It returns an error, leaving the TextRope unchanged, if the text isn't valid UTF-8.
+// It implements encoding.TextUnmarshaler.
+func (t *TextRope) UnmarshalText(text []byte) error {
+	nt, err := NewTextRope(string(text))
+	if err != nil {
+		return err
+	}
+	*t = *nt
+	return nil
+}
+
+// Len returns the length of the TextRope in bytes.
+func (t *TextRope) Len() uint32 {
+	return t.sa.Len()
+}
+
+// RuneLen returns the length of the TextRope in runes.
+func (t *TextRope) RuneLen() uint32 {
+	return t.sa.tree.text.values[t.sa.root].runes
+}
+
+// UTF16Len returns the length of the TextRope in UTF-16 code units.
+func (t *TextRope) UTF16Len() uint32 {
+	return t.sa.tree.text.values[t.sa.root].utf16
+}
+
+// ByteToRune converts a byte offset to a rune offset.
+func (t *TextRope) ByteToRune(offset uint32) (uint32, error) {
+	m, err := t.sa.tree.textSeek(t.sa.root, offset, byteMetric)
+	return m.runes, err
+}
+
+// RuneToByte converts a rune offset to a byte offset.
+func (t *TextRope) RuneToByte(offset uint32) (uint32, error) {
+	m, err := t.sa.tree.textSeek(t.sa.root, offset, runeMetric)
+	return m.bytes, err
+}
+
+// ByteToUTF16 converts a byte offset to a UTF-16 code unit offset.
+func (t *TextRope) ByteToUTF16(offset uint32) (uint32, error) {
+	m, err := t.sa.tree.textSeek(t.sa.root, offset, byteMetric)
+	return m.utf16, err
+}
+
+// UTF16ToByte converts a UTF-16 code unit offset to a byte offset.
+func (t *TextRope) UTF16ToByte(offset uint32) (uint32, error) {
+	m, err := t.sa.tree.textSeek(t.sa.root, offset, utf16Metric)
+	return m.bytes, err
+}
+
+// RuneToUTF16 converts a rune offset to a UTF-16 code unit offset.
+func (t *TextRope) RuneToUTF16(offset uint32) (uint32, error) {
+	m, err := t.sa.tree.textSeek(t.sa.root, offset, runeMetric)
+	return m.utf16, err
+}
+
+// UTF16ToRune converts a UTF-16 code unit offset to a rune offset.
+func (t *TextRope) UTF16ToRune(offset uint32) (uint32, error) {
+	m, err := t.sa.tree.textSeek(t.sa.root, offset, utf16Metric)
+	return m.runes, err
+}
+
+// Insert returns a new TextRope with the given text inserted at the given offset.
+// It returns an error if the offset splits a rune, or the text isn't valid UTF-8.
+func (t *TextRope) Insert(offset uint32, text string) (*TextRope, error) {
+	if !utf8.ValidString(text) {
+		return nil, fmt.Errorf("text is not valid UTF-8")
+	}
+	if _, err := t.sa.tree.textSeek(t.sa.root, offset, byteMetric); err != nil {
+		return nil, err
+	}
+	return &TextRope{sa: t.sa.Insert(offset, []byte(text)...)}, nil
+}
+
+// Remove returns a new TextRope with length bytes removed from the given offset.
+// It returns an error if either end of the removed range splits a rune.
+func (t *TextRope) Remove(start, length uint32) (*TextRope, error) {
+	if _, err := t.sa.tree.textSeek(t.sa.root, start, byteMetric); err != nil {
+		return nil, err
+	}
+	if _, err := t.sa.tree.textSeek(t.sa.root, checkedAdd(start, uint64(length)), byteMetric); err != nil {
+		return nil, err
+	}
+	return &TextRope{sa: t.sa.Remove(start, length)}, nil
+}
@@ -0,0 +1,150 @@
+package tree
+
+import (
+	"encoding"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+func TestTextRope(t *testing.T) {
+	text := "héllo, 世界! 👋🏽 ok"
+	tr, err := NewTextRope("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// build the rope from pieces so runs of text span several leaves
+	for _, piece := range []string{" ok", "👋🏽", "! ", "世界", "héllo, "} {
+		if tr, err = tr.Insert(0, piece); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := tr.String(); s != text {
+		t.Fatalf("Expected %q, got %q", text, s)
+	}
+	runes := []rune(text)
+	if tr.Len() != uint32(len(text)) || tr.RuneLen() != uint32(len(runes)) || tr.UTF16Len() != uint32(len(utf16.Encode(runes))) {
+		t.Errorf("Expected lengths %d %d %d, got %d %d %d", len(text), len(runes), len(utf16.Encode(runes)),
+			tr.Len(), tr.RuneLen(), tr.UTF16Len())
+	}
+
+	t.Run("conversions", func(t *testing.T) {
+		var b, u int
+		for r, c := range append(runes, 0) {
+			check := func(name string, f func(uint32) (uint32, error), from, to int) {
+				if got, err := f(uint32(from)); err != nil || got != uint32(to) {
+					t.Errorf("%s(%d): expected %d, got %d %v", name, from, to, got, err)
+				}
+			}
+			check("ByteToRune", tr.ByteToRune, b, r)
+			check("RuneToByte", tr.RuneToByte, r, b)
+			check("ByteToUTF16", tr.ByteToUTF16, b, u)
+			check("UTF16ToByte", tr.UTF16ToByte, u, b)
+			check("RuneToUTF16", tr.RuneToUTF16, r, u)
+			check("UTF16ToRune", tr.UTF16ToRune, u, r)
+			b += utf8.RuneLen(c)
+			u += utf16.RuneLen(c)
+		}
+	})
+
+	t.Run("invalid splits", func(t *testing.T) {
+		if _, err := tr.ByteToRune(2); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("Expected ErrInvalidSplit inside é, got %v", err)
+		}
+		wave, _ := tr.RuneToUTF16(uint32(len([]rune("héllo, 世界! "))))
+		if _, err := tr.UTF16ToByte(wave + 1); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("Expected ErrInvalidSplit inside a surrogate pair, got %v", err)
+		}
+		if _, err := tr.Insert(9, "x"); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("Expected ErrInvalidSplit inserting inside 世, got %v", err)
+		}
+		if _, err := tr.Remove(0, 2); !errors.Is(err, ErrInvalidSplit) {
+			t.Errorf("Expected ErrInvalidSplit removing half of é, got %v", err)
+		}
+		if _, err := tr.Insert(0, "\xff"); err == nil {
+			t.Error("Expected an error inserting invalid UTF-8")
+		}
+		if _, err := tr.ByteToRune(tr.Len() + 1); err == nil {
+			t.Error("Expected an error converting an offset past the end")
+		}
+	})
+
+	t.Run("edits", func(t *testing.T) {
+		edited, err := tr.Remove(8, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edited, err = edited.Insert(8, "мир"); err != nil {
+			t.Fatal(err)
+		}
+		if s := edited.String(); s != "héllo, мир! 👋🏽 ok" {
+			t.Errorf("Unexpected %q", s)
+		}
+		if r := edited.RuneLen(); r != uint32(utf8.RuneCountInString(edited.String())) {
+			t.Errorf("Expected %d runes, got %d", utf8.RuneCountInString(edited.String()), r)
+		}
+	})
+}
+
+func TestTextRope_StraddlingLeaves(t *testing.T) {
+	// leaves are split every BALANCED_LEAF_LENGTH bytes, which falls inside runes of this text
+	text := strings.Repeat("👋é", BALANCED_LEAF_LENGTH/2)
+	tr, err := NewTextRope(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr, err = tr.Insert(6, text); err != nil {
+		t.Fatal(err)
+	}
+	text = text[:6] + text + text[6:]
+	if tr.String() != text {
+		t.Fatal("Expected the inserted text")
+	}
+	var r, u uint32
+	for b := range uint32(len(text) + 1) {
+		got, err := tr.ByteToRune(b)
+		if b < uint32(len(text)) && !utf8.RuneStart(text[b]) {
+			if !errors.Is(err, ErrInvalidSplit) {
+				t.Errorf("ByteToRune(%d): expected ErrInvalidSplit, got %d %v", b, got, err)
+			}
+			continue
+		}
+		if err != nil || got != r {
+			t.Errorf("ByteToRune(%d): expected %d, got %d %v", b, r, got, err)
+		}
+		if got, err := tr.UTF16ToByte(u); err != nil || got != b {
+			t.Errorf("UTF16ToByte(%d): expected %d, got %d %v", u, b, got, err)
+		}
+		if b < uint32(len(text)) {
+			c, _ := utf8.DecodeRuneInString(text[b:])
+			r, u = r+1, u+uint32(utf16.RuneLen(c))
+		}
+	}
+	if tr.RuneLen() != r || tr.UTF16Len() != u {
+		t.Errorf("Expected lengths %d %d, got %d %d", r, u, tr.RuneLen(), tr.UTF16Len())
+	}
+}
+
+func TestTextRope_Text(t *testing.T) {
+	var _ encoding.TextMarshaler = &TextRope{}
+	var _ encoding.TextUnmarshaler = &TextRope{}
+
+	var tr TextRope
+	if b, err := tr.MarshalText(); err != nil || len(b) != 0 {
+		t.Errorf("Expected the zero TextRope to be empty, got %q %v", b, err)
+	}
+	if err := tr.UnmarshalText([]byte("naïve")); err != nil || tr.RuneLen() != 5 {
+		t.Errorf("Expected 5 runes, got %d %v", tr.RuneLen(), err)
+	}
+	if b, err := tr.MarshalText(); err != nil || string(b) != "naïve" {
+		t.Errorf("Expected \"naïve\", got %q %v", b, err)
+	}
+	if err := tr.UnmarshalText([]byte("\xff")); err == nil {
+		t.Error("Expected an error unmarshalling invalid UTF-8")
+	}
+	if s := tr.String(); s != "naïve" {
+		t.Errorf("Expected a failed UnmarshalText to leave the TextRope unchanged, got %q", s)
+	}
+}
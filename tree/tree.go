@@ -1,6 +1,9 @@
 package tree
 
 import (
+	"io"
+	"iter"
+	"math"
 	"unsafe"
 )
 
@@ -21,26 +24,118 @@ const INITIAL_SLAB_CAPACITY = SLAB_CHUNK_SIZE / int(NODE_BYTE_SIZE)
 // garbage collection.
 type TreeSlab struct {
 	nodes Slab[node]
+	// lengths holds the length of each (sub)tree, recorded as each node is added, which is possible as children are
+	// always added before their branches. As nodes never change once added it never needs invalidating, and reading
+	// it has no side effects, so trees can be read from any number of goroutines at once.
+	lengths *[]uint32
+	// lines is the optional newline count of each (sub)tree, maintained as nodes are added once enabled.
+	lines *byteMetrics[uint32]
+	// text is the optional rune and UTF-16 length of each (sub)tree, maintained as nodes are added once enabled.
+	text *byteMetrics[runeCounts]
 }
 
 // newTreeSlab creates a new TreeSlab with an initial capacity of INITIAL_SLAB_CAPACITY.
 func NewTreeSlab() TreeSlab {
 	ms := make(MinimalSlab[node], 0, INITIAL_SLAB_CAPACITY)
-	return TreeSlab{nodes: &ms}
+	return TreeSlab{nodes: &ms, lengths: new([]uint32)}
+}
+
+// OpenFileTreeSlab opens a TreeSlab whose nodes are stored in a FileSlab at the given path, creating it if it
+// doesn't exist. Every node added previously is restored, but it is up to the caller to keep track of which
+// node indices are roots.
+// The TreeSlab should be closed with Close when it is no longer needed.
+func OpenFileTreeSlab(path string) (TreeSlab, error) {
+	fs, err := OpenFileSlab[node](path)
+	if err != nil {
+		return TreeSlab{}, err
+	}
+	ts := TreeSlab{nodes: fs, lengths: new([]uint32)}
+	if err = ts.measure(); err != nil {
+		fs.Close()
+		return TreeSlab{}, err
+	}
+	return ts, nil
+}
+
+// Close releases any resources held by the node slab, such as a backing file.
+func (ts *TreeSlab) Close() error {
+	if c, ok := ts.nodes.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // Len returns the total number of items contained in the (sub)tree rooted at the given node index.
-func (ts *TreeSlab) Len(index uint32) (length uint32) {
-	for n := range ts.LeafIter(index) {
-		length += n.y
+// Lengths are recorded as nodes are added, so this is O(1).
+func (ts *TreeSlab) Len(index uint32) uint32 {
+	if ts.lengths != nil && index < uint32(len(*ts.lengths)) {
+		return (*ts.lengths)[index]
 	}
-	return
+	// the node was added to the slab directly, so sum its leaves, with an explicit stack so any depth of tree can be
+	// measured, and without recording anything, so reading never writes
+	var length uint32
+	stack := []uint32{index}
+	for len(stack) > 0 {
+		n := ts.nodes.Get(stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+		if n.leaf {
+			length = checkedAdd(length, uint64(n.y))
+		} else {
+			stack = append(stack, n.y, n.x)
+		}
+	}
+	return length
+}
+
+// measure records the lengths of every node added to the slab since it was last measured, in index order, so each
+// branch's children are always measured before it. It returns ErrIndexOverflow if a branch is too long.
+// Branches whose children aren't earlier nodes are recorded as having no length, in the same way as addNode.
+func (ts *TreeSlab) measure() error {
+	if ts.lengths == nil {
+		ts.lengths = new([]uint32)
+	}
+	ls := ts.lengths
+	for i := uint32(len(*ls)); i < ts.nodes.Len(); i++ {
+		n := ts.nodes.Get(i)
+		if n.leaf {
+			*ls = append(*ls, n.y)
+			continue
+		}
+		if n.x >= i || n.y >= i {
+			*ls = append(*ls, 0)
+			continue
+		}
+		if uint64((*ls)[n.x])+uint64((*ls)[n.y]) > math.MaxUint32 {
+			return ErrIndexOverflow
+		}
+		*ls = append(*ls, (*ls)[n.x]+(*ls)[n.y])
+	}
+	return nil
 }
 
-// addNode adds a node to the TreeSlab.
+// addNode adds a node to the TreeSlab, recording its length.
 // It returns the index of the added node.
+// It panics with ErrIndexOverflow if a branch would be longer than the last addressable index.
 func (ts *TreeSlab) addNode(leaf bool, x, y uint32) uint32 {
+	if err := ts.measure(); err != nil {
+		panic(err)
+	}
+	length, n := y, ts.nodes.Len()
+	if !leaf {
+		// a branch whose children aren't earlier nodes isn't part of any valid tree, and has no length to record
+		length = 0
+		if x < n && y < n {
+			length = checkedAdd(ts.Len(x), uint64(ts.Len(y)))
+		}
+	}
 	i, _ := ts.nodes.Add(node{leaf, x, y})
+	*ts.lengths = append(*ts.lengths, length)
+	if ts.lines != nil {
+		ts.lines.update(ts)
+	}
+	if ts.text != nil {
+		ts.text.update(ts)
+	}
 	return i
 }
 
@@ -52,7 +147,9 @@ func (ts *TreeSlab) addBranch(left, right uint32) uint32 {
 
 // AddLeaf adds a leaf node to the TreeSlab, as a convenience method.
 // It returns the index of the added node.
+// It panics with ErrIndexOverflow if the leaf would extend past the last addressable index.
 func (ts *TreeSlab) AddLeaf(index, length uint32) uint32 {
+	checkedAdd(index, uint64(length))
 	return ts.addNode(true, index, length)
 }
 
@@ -93,7 +190,8 @@ func (ts *TreeSlab) insert(root_index, insert_index, new_node_index uint32) uint
 }
 
 // Remove removes a range of indices from the specified (sub)tree, returning a new leaf or branch node index, or nil
-// if the node is entirely removed.
+// if the node is entirely removed. The index returned is never a reference into the node slab, so it remains valid
+// however the slab grows.
 func (ts *TreeSlab) Remove(index, start, length uint32) *uint32 {
 	// TODO: handle invalid inputs
 	// short circuit out if the entire node is removed
@@ -117,7 +215,8 @@ func (ts *TreeSlab) Remove(index, start, length uint32) *uint32 {
 	if start >= l_len {
 		r := ts.Remove(ts.nodes.Get(index).y, start-l_len, length)
 		if r == nil {
-			return &ts.nodes.GetRef(index).x
+			i := ts.nodes.Get(index).x
+			return &i
 		}
 		bi := ts.addBranch(ts.nodes.Get(index).x, *r)
 		return &bi
@@ -127,7 +226,8 @@ func (ts *TreeSlab) Remove(index, start, length uint32) *uint32 {
 	if start+length <= l_len {
 		l := ts.Remove(ts.nodes.Get(index).x, start, length)
 		if l == nil {
-			return &ts.nodes.GetRef(index).y
+			i := ts.nodes.Get(index).y
+			return &i
 		}
 		bi := ts.addBranch(*l, ts.nodes.Get(index).y)
 		return &bi
@@ -136,18 +236,59 @@ func (ts *TreeSlab) Remove(index, start, length uint32) *uint32 {
 	// removing from both sides of the branch
 	li := ts.Remove(ts.nodes.Get(index).x, start, l_len-start)
 	ri := ts.Remove(ts.nodes.Get(index).y, 0, length-(l_len-start))
+	// one side may have been removed entirely, but not both, as that would have been caught above
+	if li == nil {
+		return ri
+	}
+	if ri == nil {
+		return li
+	}
 	bi := ts.addBranch(*li, *ri)
 	return &bi
 }
 
-// WalkTree is a recursive function that walks the tree starting at a given index.
-// It calls the given function on each node in the tree.
+// buildBalanced builds a balanced tree from the given nodes, in order, returning the index of its root.
+// The slice of nodes is used as scratch space. At least one node must be given.
+func (ts *TreeSlab) buildBalanced(nodes []uint32) uint32 {
+	for len(nodes) > 1 {
+		for i := 0; i < len(nodes)/2; i++ {
+			nodes[i] = ts.addBranch(nodes[i*2], nodes[i*2+1])
+		}
+		if len(nodes)%2 == 1 {
+			nodes[len(nodes)/2] = nodes[len(nodes)-1]
+			nodes = nodes[:len(nodes)/2+1]
+		} else {
+			nodes = nodes[:len(nodes)/2]
+		}
+	}
+	return nodes[0]
+}
+
+// WalkTree walks the tree starting at a given index in pre-order.
+// It calls the given function on each node in the tree. See Walk for more control over the traversal.
 func (ts *TreeSlab) WalkTree(index uint32, f func(*node)) {
-	f(ts.nodes.GetRef(index))
-	if !ts.nodes.Get(index).leaf {
-		ts.WalkTree(ts.nodes.Get(index).x, f)
-		ts.WalkTree(ts.nodes.Get(index).y, f)
+	ts.walk(index, func(n *node) bool {
+		f(n)
+		return true
+	})
+}
+
+// walk walks the tree in the same order as WalkTree, but stops as soon as the given function returns false.
+// It returns false if the walk was stopped early.
+// It uses an explicit stack rather than recursion, so it copes with trees of any depth.
+func (ts *TreeSlab) walk(index uint32, f func(*node) bool) bool {
+	stack := []uint32{index}
+	for len(stack) > 0 {
+		n := ts.nodes.GetRef(stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+		if !f(n) {
+			return false
+		}
+		if !n.leaf {
+			stack = append(stack, n.y, n.x)
+		}
 	}
+	return true
 }
 
 // TODO: remove this? rename it at least, if it really serves any purpose...
@@ -159,31 +300,202 @@ func (ts *TreeSlab) GetLeaves(index uint32) (leaves []node) {
 	return
 }
 
-// LeafIter returns a channel that iterates over the leaf nodes in the (sub)tree starting at a given node index.
-func (ts *TreeSlab) LeafIter(index uint32) chan *node {
-	c := make(chan *node, 4)
-	go func() {
-		ts.WalkTree(index, func(n *node) {
-			if n.leaf {
-				c <- n
-			}
+// LeafIter returns an iterator over the leaf nodes in the (sub)tree starting at a given node index.
+func (ts *TreeSlab) LeafIter(index uint32) iter.Seq[*node] {
+	return func(yield func(*node) bool) {
+		ts.walk(index, func(n *node) bool {
+			return !n.leaf || yield(n)
 		})
-		close(c)
-	}()
-	return c
+	}
 }
 
-// IndexIter returns a channel that iterates over the indices represented by the leaf nodes in the (sub)tree starting
-// at a given node index.
-func (ts *TreeSlab) IndexIter(index uint32) chan uint32 {
-	c := make(chan uint32, 64)
-	go func() {
+// IndexIter returns an iterator over the indices represented by the leaf nodes in the (sub)tree starting at a given
+// node index.
+func (ts *TreeSlab) IndexIter(index uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
 		for n := range ts.LeafIter(index) {
 			for i := n.x; i < n.x+n.y; i++ {
-				c <- i
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// LeafSpans returns an iterator over the items of the (sub)tree starting at a given node index, as one span per leaf
+// node. If the data slab is a ContiguousSlab the spans are views into it, otherwise leaves are yielded as the spans
+// the data slab's SpanIter produces.
+func LeafSpans[T any](ts *TreeSlab, index uint32, data Slab[T]) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		cs, contiguous := data.(ContiguousSlab[T])
+		for n := range ts.LeafIter(index) {
+			if contiguous {
+				if !yield(cs.Span(n.x, n.y)) {
+					return
+				}
+				continue
+			}
+			for span := range data.SpanIter(n.x, n.y) {
+				if !yield(span) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ReverseLeafIter returns an iterator over the leaf nodes covering the positions before end in the (sub)tree starting
+// at a given node index, from right to left. The first leaf yielded is clipped to end if end falls inside it, in
+// which case a reference to a clipped copy of the leaf is yielded.
+func (ts *TreeSlab) ReverseLeafIter(index, end uint32) iter.Seq[*node] {
+	return func(yield func(*node) bool) {
+		ts.reverseWalk(index, end, false, yield)
+	}
+}
+
+// reverseWalk yields the leaves before end of the given subtree from right to left, or every leaf if all is set.
+// It returns false if the walk was stopped early.
+func (ts *TreeSlab) reverseWalk(index, end uint32, all bool, yield func(*node) bool) bool {
+	n := ts.nodes.GetRef(index)
+	if n.leaf {
+		if all || end >= n.y {
+			return yield(n)
+		}
+		if end == 0 {
+			return true
+		}
+		return yield(&node{leaf: true, x: n.x, y: end})
+	}
+	if all {
+		return ts.reverseWalk(n.y, 0, true, yield) && ts.reverseWalk(n.x, 0, true, yield)
+	}
+	l_len := ts.Len(n.x)
+	if end <= l_len {
+		return ts.reverseWalk(n.x, end, false, yield)
+	}
+	return ts.reverseWalk(n.y, end-l_len, false, yield) && ts.reverseWalk(n.x, 0, true, yield)
+}
+
+// ReverseIndexIter returns an iterator over the indices represented by the positions before end in the (sub)tree
+// starting at a given node index, from right to left.
+func (ts *TreeSlab) ReverseIndexIter(index, end uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		for n := range ts.ReverseLeafIter(index, end) {
+			for i := n.x + n.y; i > n.x; i-- {
+				if !yield(i - 1) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ReverseElements returns an iterator over the items at the positions before end in the (sub)tree starting at a given
+// node index, from right to left.
+func ReverseElements[T any](ts *TreeSlab, index, end uint32, data Slab[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for i := range ts.ReverseIndexIter(index, end) {
+			if !yield(data.Get(i)) {
+				return
+			}
+		}
+	}
+}
+
+// RangeIter returns an iterator over the leaf nodes covering the positions [start, start+length) of the (sub)tree
+// starting at a given node index. It descends directly to the first leaf in the range, skipping any subtrees outside
+// of it. Leaves partially inside the range are clipped, in which case a reference to a clipped copy is yielded.
+func (ts *TreeSlab) RangeIter(index, start, length uint32) iter.Seq[*node] {
+	return func(yield func(*node) bool) {
+		ts.rangeWalk(index, start, length, yield)
+	}
+}
+
+// rangeWalk yields the leaves covering [start, start+length) of the given subtree from left to right.
+// It returns false if the walk was stopped early.
+func (ts *TreeSlab) rangeWalk(index, start, length uint32, yield func(*node) bool) bool {
+	if length == 0 {
+		return true
+	}
+	n := ts.nodes.GetRef(index)
+	if n.leaf {
+		if start >= n.y {
+			return true
+		}
+		if start == 0 && length >= n.y {
+			return yield(n)
+		}
+		return yield(&node{leaf: true, x: n.x + start, y: min(length, n.y-start)})
+	}
+	l_len := ts.Len(n.x)
+	if start >= l_len {
+		return ts.rangeWalk(n.y, start-l_len, length, yield)
+	}
+	l := min(length, l_len-start)
+	return ts.rangeWalk(n.x, start, l, yield) && ts.rangeWalk(n.y, 0, length-l, yield)
+}
+
+// RangeIndexIter returns an iterator over the indices represented by the positions [start, start+length) of the
+// (sub)tree starting at a given node index.
+func (ts *TreeSlab) RangeIndexIter(index, start, length uint32) iter.Seq[uint32] {
+	return func(yield func(uint32) bool) {
+		for n := range ts.RangeIter(index, start, length) {
+			for i := n.x; i < n.x+n.y; i++ {
+				if !yield(i) {
+					return
+				}
 			}
 		}
-		close(c)
-	}()
-	return c
+	}
+}
+
+// SpanIter returns an iterator over the contiguous runs of data slab indices represented by the leaf nodes in the
+// (sub)tree starting at a given node index, as (start, length) pairs.
+func (ts *TreeSlab) SpanIter(index uint32) iter.Seq2[uint32, uint32] {
+	return func(yield func(uint32, uint32) bool) {
+		for n := range ts.LeafIter(index) {
+			if !yield(n.x, n.y) {
+				return
+			}
+		}
+	}
+}
+
+// RangeSpanIter returns an iterator over the contiguous runs of data slab indices represented by the positions
+// [start, start+length) of the (sub)tree starting at a given node index, as (start, length) pairs.
+// Runs partially inside the range are clipped to it.
+func (ts *TreeSlab) RangeSpanIter(index, start, length uint32) iter.Seq2[uint32, uint32] {
+	return func(yield func(uint32, uint32) bool) {
+		for n := range ts.RangeIter(index, start, length) {
+			if !yield(n.x, n.y) {
+				return
+			}
+		}
+	}
+}
+
+// WriteSpans writes the bytes at the positions [start, start+length) of the (sub)tree starting at a given node index
+// to w, one contiguous run at a time, returning the number of bytes written.
+// If the data slab is a ContiguousSlab each run is written directly from it, without copying.
+func WriteSpans(w io.Writer, ts *TreeSlab, index uint32, data Slab[byte], start, length uint32) (written int64, err error) {
+	cs, contiguous := data.(ContiguousSlab[byte])
+	for s, l := range ts.RangeSpanIter(index, start, length) {
+		if contiguous {
+			n, err := w.Write(cs.Span(s, l))
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+			continue
+		}
+		for span := range data.SpanIter(s, l) {
+			n, err := w.Write(span)
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+	return
 }
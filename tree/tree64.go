@@ -0,0 +1,319 @@
+package tree
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"unsafe"
+)
+
+// Slab64 is the interface for slabs which can hold more than 2^32 items, addressed by uint64 indices.
+// It mirrors Slab in every other respect.
+type Slab64[T any] interface {
+	// Add adds items to the slab and returns the start index and number of added items.
+	Add(items ...T) (start uint64, length uint64)
+	// Get returns the item at the given index.
+	Get(index uint64) T
+	// GetRef returns a reference to the item at the given index.
+	GetRef(index uint64) *T
+	// Set replaces the item at the given index.
+	Set(index uint64, item T)
+	// Len returns the total number of items in the slab.
+	Len() uint64
+	// SliceIter returns an iterator over length items from the start index.
+	SliceIter(start uint64, length uint64) iter.Seq[T]
+	// SpanIter returns an iterator over length items from the start index as a series of spans.
+	SpanIter(start uint64, length uint64) iter.Seq[[]T]
+}
+
+// MinimalSlab64 is the bare minimum implementation of a Slab64.
+type MinimalSlab64[T any] []T
+
+func (s *MinimalSlab64[T]) Add(items ...T) (start uint64, length uint64) {
+	start = uint64(len(*s))
+	length = uint64(len(items))
+	*s = append(*s, items...)
+	return
+}
+
+func (s MinimalSlab64[T]) Get(index uint64) T {
+	return s[index]
+}
+
+func (s MinimalSlab64[T]) GetRef(index uint64) *T {
+	return &s[index]
+}
+
+func (s MinimalSlab64[T]) Set(index uint64, item T) {
+	s[index] = item
+}
+
+func (s MinimalSlab64[T]) Len() uint64 {
+	return uint64(len(s))
+}
+
+func (s MinimalSlab64[T]) SliceIter(start uint64, length uint64) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, x := range s[start : start+length] {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
+
+func (s MinimalSlab64[T]) Span(start uint64, length uint64) []T {
+	return s[start : start+length : start+length]
+}
+
+func (s MinimalSlab64[T]) SpanIter(start uint64, length uint64) iter.Seq[[]T] {
+	return spanOf(s.Span(start, length))
+}
+
+// node64 is the 64 bit equivalent of node, used by TreeSlab64.
+type node64 struct {
+	leaf bool
+	x, y uint64
+}
+
+// String() returns a string representation of the node.
+func (n *node64) String() string {
+	if n.leaf {
+		return fmt.Sprintf("leaf {index: %d length: %d}", n.x, n.y)
+	} else {
+		return fmt.Sprintf("branch {left: %d right: %d}", n.x, n.y)
+	}
+}
+
+// remove removes a range of indices from a leaf node, in the same way as node.remove.
+func (n node64) remove(start, length uint64) (*node64, *node64) {
+	if length == n.y {
+		return nil, nil
+	}
+	if start == 0 {
+		return &node64{leaf: true, x: n.x + length, y: n.y - length}, nil
+	}
+	if start+length == n.y {
+		return &node64{leaf: true, x: n.x, y: n.y - length}, nil
+	}
+	return &node64{leaf: true, x: n.x, y: start}, &node64{leaf: true, x: n.x + start + length, y: n.y - (start + length)}
+}
+
+// NODE64_BYTE_SIZE is the size of a node64 in bytes.
+const NODE64_BYTE_SIZE = unsafe.Sizeof(node64{})
+
+// TreeSlab64 is the 64 bit equivalent of TreeSlab, for sequences, or data slabs, of more than 2^32 items.
+// Its nodes are larger, so TreeSlab should be preferred wherever uint32 indices are sufficient.
+type TreeSlab64 struct {
+	nodes Slab64[node64]
+	// lengths holds the length of each (sub)tree, recorded as each node is added, in the same way as TreeSlab.
+	lengths *[]uint64
+}
+
+// NewTreeSlab64 creates a new TreeSlab64 with an initial capacity of SLAB_CHUNK_SIZE bytes worth of nodes.
+func NewTreeSlab64() TreeSlab64 {
+	ms := make(MinimalSlab64[node64], 0, SLAB_CHUNK_SIZE/int(NODE64_BYTE_SIZE))
+	return TreeSlab64{nodes: &ms, lengths: new([]uint64)}
+}
+
+// checkedAdd64 returns a+b, panicking with ErrIndexOverflow if the sum would overflow a uint64.
+func checkedAdd64(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		panic(ErrIndexOverflow)
+	}
+	return a + b
+}
+
+// Len returns the total number of items contained in the (sub)tree rooted at the given node index.
+func (ts *TreeSlab64) Len(index uint64) uint64 {
+	if ts.lengths != nil && index < uint64(len(*ts.lengths)) {
+		return (*ts.lengths)[index]
+	}
+	// the node was added to the slab directly, so sum its leaves as TreeSlab.Len does, without recording anything
+	var length uint64
+	stack := []uint64{index}
+	for len(stack) > 0 {
+		n := ts.nodes.Get(stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+		if n.leaf {
+			length = checkedAdd64(length, n.y)
+		} else {
+			stack = append(stack, n.y, n.x)
+		}
+	}
+	return length
+}
+
+// measure records the lengths of every node added to the slab since it was last measured, in the same way as
+// TreeSlab.measure.
+func (ts *TreeSlab64) measure() error {
+	if ts.lengths == nil {
+		ts.lengths = new([]uint64)
+	}
+	ls := ts.lengths
+	for i := uint64(len(*ls)); i < ts.nodes.Len(); i++ {
+		n := ts.nodes.Get(i)
+		if n.leaf {
+			*ls = append(*ls, n.y)
+			continue
+		}
+		if n.x >= i || n.y >= i {
+			*ls = append(*ls, 0)
+			continue
+		}
+		if (*ls)[n.x] > math.MaxUint64-(*ls)[n.y] {
+			return ErrIndexOverflow
+		}
+		*ls = append(*ls, (*ls)[n.x]+(*ls)[n.y])
+	}
+	return nil
+}
+
+// addNode adds a node to the TreeSlab64, recording its length.
+// It returns the index of the added node.
+// It panics with ErrIndexOverflow if a branch would be longer than the last addressable index.
+func (ts *TreeSlab64) addNode(leaf bool, x, y uint64) uint64 {
+	if err := ts.measure(); err != nil {
+		panic(err)
+	}
+	length, n := y, ts.nodes.Len()
+	if !leaf {
+		length = 0
+		if x < n && y < n {
+			length = checkedAdd64(ts.Len(x), ts.Len(y))
+		}
+	}
+	i, _ := ts.nodes.Add(node64{leaf, x, y})
+	*ts.lengths = append(*ts.lengths, length)
+	return i
+}
+
+// addBranch adds a branch node to the TreeSlab64, as a convenience method.
+// It returns the index of the added node.
+func (ts *TreeSlab64) addBranch(left, right uint64) uint64 {
+	return ts.addNode(false, left, right)
+}
+
+// AddLeaf adds a leaf node to the TreeSlab64, as a convenience method.
+// It returns the index of the added node.
+func (ts *TreeSlab64) AddLeaf(index, length uint64) uint64 {
+	return ts.addNode(true, index, length)
+}
+
+// insert inserts a new node into the TreeSlab64, in the same way as TreeSlab.insert.
+func (ts *TreeSlab64) insert(root_index, insert_index, new_node_index uint64) uint64 {
+	if insert_index == 0 {
+		return ts.addBranch(new_node_index, root_index)
+	}
+	if insert_index == ts.Len(root_index) {
+		return ts.addBranch(root_index, new_node_index)
+	}
+	if ts.nodes.Get(root_index).leaf {
+		l, r := ts.nodes.Get(root_index).remove(insert_index, 0)
+		return ts.addBranch(ts.AddLeaf(l.x, l.y), ts.addBranch(new_node_index, ts.AddLeaf(r.x, r.y)))
+	}
+	bn := ts.nodes.Get(root_index)
+	l_len := ts.Len(bn.x)
+	if insert_index == l_len {
+		return ts.addBranch(bn.x, ts.addBranch(new_node_index, bn.y))
+	}
+	if insert_index < l_len {
+		return ts.addBranch(ts.insert(bn.x, insert_index, new_node_index), bn.y)
+	}
+	return ts.addBranch(bn.x, ts.insert(bn.y, insert_index-l_len, new_node_index))
+}
+
+// Remove removes a range of indices from the specified (sub)tree, in the same way as TreeSlab.Remove.
+func (ts *TreeSlab64) Remove(index, start, length uint64) *uint64 {
+	if start == 0 && length == ts.Len(index) {
+		return nil
+	}
+	if ts.nodes.Get(index).leaf {
+		l, r := ts.nodes.Get(index).remove(start, length)
+		if r == nil {
+			i := ts.AddLeaf(l.x, l.y)
+			return &i
+		}
+		i := ts.addBranch(ts.AddLeaf(l.x, l.y), ts.AddLeaf(r.x, r.y))
+		return &i
+	}
+	l_len := ts.Len(ts.nodes.Get(index).x)
+	if start >= l_len {
+		r := ts.Remove(ts.nodes.Get(index).y, start-l_len, length)
+		if r == nil {
+			i := ts.nodes.Get(index).x
+			return &i
+		}
+		bi := ts.addBranch(ts.nodes.Get(index).x, *r)
+		return &bi
+	}
+	if start+length <= l_len {
+		l := ts.Remove(ts.nodes.Get(index).x, start, length)
+		if l == nil {
+			i := ts.nodes.Get(index).y
+			return &i
+		}
+		bi := ts.addBranch(*l, ts.nodes.Get(index).y)
+		return &bi
+	}
+	li := ts.Remove(ts.nodes.Get(index).x, start, l_len-start)
+	ri := ts.Remove(ts.nodes.Get(index).y, 0, length-(l_len-start))
+	if li == nil {
+		return ri
+	}
+	if ri == nil {
+		return li
+	}
+	bi := ts.addBranch(*li, *ri)
+	return &bi
+}
+
+// WalkTree walks the tree starting at a given index in pre-order.
+// It calls the given function on each node in the tree.
+func (ts *TreeSlab64) WalkTree(index uint64, f func(*node64)) {
+	ts.walk(index, func(n *node64) bool {
+		f(n)
+		return true
+	})
+}
+
+// walk walks the tree in the same order as WalkTree, but stops as soon as the given function returns false.
+// It returns false if the walk was stopped early.
+// It uses an explicit stack rather than recursion, in the same way as TreeSlab.walk.
+func (ts *TreeSlab64) walk(index uint64, f func(*node64) bool) bool {
+	stack := []uint64{index}
+	for len(stack) > 0 {
+		n := ts.nodes.GetRef(stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+		if !f(n) {
+			return false
+		}
+		if !n.leaf {
+			stack = append(stack, n.y, n.x)
+		}
+	}
+	return true
+}
+
+// LeafIter returns an iterator over the leaf nodes in the (sub)tree starting at a given node index.
+func (ts *TreeSlab64) LeafIter(index uint64) iter.Seq[*node64] {
+	return func(yield func(*node64) bool) {
+		ts.walk(index, func(n *node64) bool {
+			return !n.leaf || yield(n)
+		})
+	}
+}
+
+// IndexIter returns an iterator over the indices represented by the leaf nodes in the (sub)tree starting at a given
+// node index.
+func (ts *TreeSlab64) IndexIter(index uint64) iter.Seq[uint64] {
+	return func(yield func(uint64) bool) {
+		for n := range ts.LeafIter(index) {
+			for i := n.x; i < n.x+n.y; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package tree
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// generateBalancedTree64 is the TreeSlab64 equivalent of generateBalancedTree, which copes with width + depth > 32.
+func generateBalancedTree64(depth uint64, width uint64) (ts TreeSlab64, root uint64) {
+	ts = NewTreeSlab64()
+	depth = 1 << depth
+	width = 1 << width
+	nodes := make([]uint64, 0, depth)
+	for i := uint64(0); i < depth; i++ {
+		nodes = append(nodes, ts.AddLeaf(i*width, width))
+	}
+	for x := depth; x > 1; x /= 2 {
+		for i := uint64(0); i < x/2; i++ {
+			nodes[i] = ts.addBranch(nodes[i*2], nodes[i*2+1])
+		}
+	}
+	root = nodes[0]
+	return
+}
+
+func TestTreeSlab64_Len(t *testing.T) {
+	ts, root := generateBalancedTree64(4, 32)
+	if ts.Len(root) != 1<<36 {
+		t.Error("Expected", uint64(1<<36), "got", ts.Len(root))
+	}
+}
+
+func TestTreeSlab64_Lengths(t *testing.T) {
+	// a chain of branches deep enough that a recursive walk would be slow to measure at every level
+	ts := NewTreeSlab64()
+	root := ts.AddLeaf(0, 1<<40)
+	for i := uint64(1); i <= 100_000; i++ {
+		root = ts.addBranch(root, ts.AddLeaf(i, 1))
+	}
+	if ts.Len(root) != 1<<40+100_000 {
+		t.Error("Expected", uint64(1<<40+100_000), "got", ts.Len(root))
+	}
+	leaves := 0
+	for range ts.LeafIter(root) {
+		leaves++
+	}
+	if leaves != 100_001 {
+		t.Error("Expected 100001 leaves, got", leaves)
+	}
+
+	// nodes added to the slab directly are measured without being recorded, then caught up on the next add
+	i, _ := ts.nodes.Add(node64{false, root, 0})
+	want := ts.Len(root) + ts.Len(0)
+	if ts.Len(i) != want || uint64(len(*ts.lengths)) != i {
+		t.Error("Expected the unrecorded node to be measured on demand, got", ts.Len(i))
+	}
+	if j := ts.AddLeaf(0, 2); ts.Len(j) != 2 || ts.Len(i) != want || uint64(len(*ts.lengths)) != j+1 {
+		t.Error("Expected the lengths to be caught up")
+	}
+}
+
+func TestTreeSlab64_Insert(t *testing.T) {
+	ts := NewTreeSlab64()
+	root := ts.AddLeaf(0, 1<<33)
+	root = ts.insert(root, 1<<32, ts.AddLeaf(1<<33, 10))
+	expected := [][2]uint64{{0, 1 << 32}, {1 << 33, 10}, {1 << 32, 1 << 32}}
+	i := 0
+	for n := range ts.LeafIter(root) {
+		if n.x != expected[i][0] || n.y != expected[i][1] {
+			t.Errorf("leaf %d expected %d:%d, got %d:%d", i, expected[i][0], expected[i][1], n.x, n.y)
+		}
+		i++
+	}
+}
+
+func TestTreeSlab64_Remove(t *testing.T) {
+	ts := NewTreeSlab64()
+	root := ts.addBranch(ts.AddLeaf(0, 1<<32), ts.AddLeaf(1<<32, 1<<32))
+	n := ts.Remove(root, 1<<31, 1<<32)
+	if ts.Len(*n) != 1<<32 {
+		t.Error("Expected", uint64(1<<32), "got", ts.Len(*n))
+	}
+	leaves := []string{"leaf {index: 0 length: 2147483648}", "leaf {index: 6442450944 length: 2147483648}"}
+	i := 0
+	for l := range ts.LeafIter(*n) {
+		if l.String() != leaves[i] {
+			t.Errorf("Expected %s, got %s", leaves[i], l.String())
+		}
+		i++
+	}
+
+	t.Run("all of one side", func(t *testing.T) {
+		ts := NewTreeSlab64()
+		root := ts.addBranch(ts.AddLeaf(0, 5), ts.AddLeaf(5, 5))
+		n := ts.Remove(root, 2, 8)
+		if n == nil {
+			t.Fatal("Expected a leaf, got nil")
+		}
+		if l := ts.nodes.Get(*n); l.String() != "leaf {index: 0 length: 2}" {
+			t.Errorf("Expected leaf {index: 0 length: 2}, got %s", l.String())
+		}
+		n = ts.Remove(root, 0, 7)
+		if l := ts.nodes.Get(*n); l.String() != "leaf {index: 7 length: 3}" {
+			t.Errorf("Expected leaf {index: 7 length: 3}, got %s", l.String())
+		}
+	})
+}
+
+func TestTreeSlab64_IndexIter(t *testing.T) {
+	ts, idx := generateBalancedTree64(4, 4)
+	i := uint64(0)
+	for n := range ts.IndexIter(idx) {
+		if n != i {
+			t.Error("Expected", i, "got", n)
+		}
+		i++
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	expectOverflow := func(t *testing.T, f func()) {
+		t.Helper()
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, ErrIndexOverflow) {
+				t.Error("Expected ErrIndexOverflow, got", err)
+			}
+		}()
+		f()
+	}
+
+	t.Run("leaf", func(t *testing.T) {
+		ts := NewTreeSlab()
+		expectOverflow(t, func() { ts.AddLeaf(math.MaxUint32, 2) })
+	})
+
+	t.Run("branch", func(t *testing.T) {
+		ts := NewTreeSlab()
+		l, r := ts.AddLeaf(0, math.MaxUint32), ts.AddLeaf(0, math.MaxUint32)
+		expectOverflow(t, func() { ts.addBranch(l, r) })
+		if n := ts.nodes.Len(); n != 2 {
+			t.Error("Expected the overflowing branch not to be added, got", n, "nodes")
+		}
+	})
+
+	t.Run("slab", func(t *testing.T) {
+		s := BitSlab{n: math.MaxUint32 - 1}
+		expectOverflow(t, func() { s.Add(true, true) })
+	})
+}
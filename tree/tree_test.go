@@ -3,12 +3,13 @@ package tree
 import (
 	"math/rand"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 )
 
 // generates a balanced tree with 2^depth leaves with length 2^width.
-// Note that if width + depth > 32, the tree will explode.
+// Note that if width + depth > 32, the indices overflow uint32; use generateBalancedTree64 for trees that large.
 func generateBalancedTree(depth uint32, width uint32) (ts TreeSlab, root uint32) {
 	// TODO: perhaps try to avoid exploding with depth over (or near) 32...
 	ts = NewTreeSlab()
@@ -111,6 +112,23 @@ func TestLen(t *testing.T) {
 	})
 }
 
+// TestConcurrentReads checks reads have no side effects, which the race detector would catch.
+func TestConcurrentReads(t *testing.T) {
+	sa := NewSpliceArray(make([]int, 5000)...).Insert(2500, 1, 2, 3).Remove(100, 50)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pos := uint32(0); pos < sa.Len(); pos += 97 {
+				sa.Get(pos)
+				sa.tree.NewCursor(sa.root).Seek(pos)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestAddNode(t *testing.T) {
 	ts := NewTreeSlab()
 	bi := ts.addNode(false, 0, 0)
@@ -381,6 +399,17 @@ func TestTreeRemove(t *testing.T) {
 			}
 		})
 
+		t.Run("all of one side", func(t *testing.T) {
+			n := ts.nodes.Get(*ts.Remove(root, 5, 15))
+			if n.String() != "leaf {index: 0 length: 5}" {
+				t.Errorf("Expected leaf {index: 0 length: 5}, got %s", n.String())
+			}
+			n = ts.nodes.Get(*ts.Remove(root, 0, 15))
+			if n.String() != "leaf {index: 15 length: 5}" {
+				t.Errorf("Expected leaf {index: 15 length: 5}, got %s", n.String())
+			}
+		})
+
 		t.Run("middle", func(t *testing.T) {
 			n := ts.Remove(root, 5, 10)
 			leaves := ts.GetLeaves(*n)
@@ -532,3 +561,274 @@ func BenchmarkIndexIter(b *testing.B) {
    })
 }
 
+
+func TestLeafSpans(t *testing.T) {
+	ts, idx := generateBalancedTree(4, 4)
+	data := MinimalSlab[uint32]{}
+	cdata := NewCompressedSlab[uint32]()
+	for i := uint32(0); i < 1<<8; i++ {
+		data.Add(i)
+		cdata.Add(i)
+	}
+	for _, s := range []Slab[uint32]{&data, cdata} {
+		i := uint32(0)
+		for span := range LeafSpans(&ts, idx, s) {
+			for _, x := range span {
+				if x != i {
+					t.Error("Expected", i, "got", x)
+				}
+				i++
+			}
+		}
+		if i != 1<<8 {
+			t.Error("Expected 256 items, got", i)
+		}
+	}
+}
+
+func TestIterEarlyStop(t *testing.T) {
+	ts, idx := generateBalancedTree(4, 4)
+	t.Run("leaves", func(t *testing.T) {
+		i := 0
+		for range ts.LeafIter(idx) {
+			i++
+			if i == 3 {
+				break
+			}
+		}
+		if i != 3 {
+			t.Error("Expected 3 leaves, got", i)
+		}
+	})
+
+	t.Run("indices", func(t *testing.T) {
+		var last uint32
+		for n := range ts.IndexIter(idx) {
+			last = n
+			if n == 40 {
+				break
+			}
+		}
+		if last != 40 {
+			t.Error("Expected to stop at 40, stopped at", last)
+		}
+	})
+
+	t.Run("elements", func(t *testing.T) {
+		s := MinimalSlab[int]{}
+		s.Add(1, 2, 3, 4, 5)
+		sum := 0
+		for x := range s.SliceIter(1, 4) {
+			sum += x
+			if x == 3 {
+				break
+			}
+		}
+		if sum != 5 {
+			t.Error("Expected 5, got", sum)
+		}
+	})
+}
+
+// chanIndexIter is the goroutine and channel based IndexIter which the push iterators replaced, kept to benchmark
+// against.
+func chanIndexIter(ts *TreeSlab, index uint32) chan uint32 {
+	leaves := make(chan *node, 4)
+	go func() {
+		ts.WalkTree(index, func(n *node) {
+			if n.leaf {
+				leaves <- n
+			}
+		})
+		close(leaves)
+	}()
+	c := make(chan uint32, 64)
+	go func() {
+		for n := range leaves {
+			for i := n.x; i < n.x+n.y; i++ {
+				c <- i
+			}
+		}
+		close(c)
+	}()
+	return c
+}
+
+func BenchmarkIndexIterChan(b *testing.B) {
+	b.Run("balanced deep", func(b *testing.B) {
+		ts, idx := generateBalancedTree(12, 4)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			for i := range chanIndexIter(&ts, idx) {
+				_ = i
+			}
+		}
+	})
+
+	b.Run("balanced wide", func(b *testing.B) {
+		ts, idx := generateBalancedTree(4, 12)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			for i := range chanIndexIter(&ts, idx) {
+				_ = i
+			}
+		}
+	})
+}
+
+func TestReverseIter(t *testing.T) {
+	ts, idx := generateUnbalancedTree(5, 3, 0)
+	var forward []uint32
+	for i := range ts.IndexIter(idx) {
+		forward = append(forward, i)
+	}
+
+	for _, end := range []uint32{uint32(len(forward)), 0, 1, 7, 8, 9, 100, 255} {
+		var reverse []uint32
+		for i := range ts.ReverseIndexIter(idx, end) {
+			reverse = append(reverse, i)
+		}
+		if len(reverse) != int(end) {
+			t.Fatal("Expected", end, "indices, got", len(reverse))
+		}
+		for j, i := range reverse {
+			if i != forward[int(end)-1-j] {
+				t.Error("Expected", forward[int(end)-1-j], "got", i, "from end", end)
+			}
+		}
+	}
+
+	t.Run("leaves", func(t *testing.T) {
+		ts := NewTreeSlab()
+		root := ts.addBranch(ts.AddLeaf(0, 10), ts.addBranch(ts.AddLeaf(20, 5), ts.AddLeaf(10, 10)))
+		expected := []string{"leaf {index: 10 length: 3}", "leaf {index: 20 length: 5}", "leaf {index: 0 length: 10}"}
+		i := 0
+		for n := range ts.ReverseLeafIter(root, 18) {
+			if n.String() != expected[i] {
+				t.Errorf("Expected %s, got %s", expected[i], n.String())
+			}
+			i++
+		}
+		if ts.nodes.Get(ts.nodes.Get(ts.nodes.Get(root).y).y).y != 10 {
+			t.Error("Expected clipping not to modify the tree")
+		}
+	})
+
+	t.Run("elements", func(t *testing.T) {
+		data := MinimalSlab[rune]{}
+		ts := NewTreeSlab()
+		root := ts.AddLeaf(data.Add([]rune("world")...))
+		root = ts.insert(root, 0, ts.AddLeaf(data.Add([]rune("hello ")...)))
+		s := ""
+		for r := range ReverseElements(&ts, root, 8, &data) {
+			s += string(r)
+		}
+		if s != "ow olleh" {
+			t.Error("Expected \"ow olleh\", got", s)
+		}
+	})
+}
+
+func TestRangeIter(t *testing.T) {
+	ts, idx := generateUnbalancedTree(5, 3, 0)
+	var all []uint32
+	for i := range ts.IndexIter(idx) {
+		all = append(all, i)
+	}
+
+	for _, r := range [][2]uint32{{0, 256}, {0, 0}, {0, 1}, {3, 10}, {8, 8}, {7, 2}, {100, 156}, {250, 100}, {300, 1}} {
+		start, length := r[0], r[1]
+		expected := all[min(start, 256):min(start+length, 256)]
+		i := 0
+		for n := range ts.RangeIndexIter(idx, start, length) {
+			if i >= len(expected) || n != expected[i] {
+				t.Fatal("Unexpected index", n, "at", i, "of range", r)
+			}
+			i++
+		}
+		if i != len(expected) {
+			t.Error("Expected", len(expected), "indices, got", i, "for range", r)
+		}
+	}
+
+	t.Run("clipped leaves", func(t *testing.T) {
+		ts := NewTreeSlab()
+		root := ts.addBranch(ts.AddLeaf(0, 10), ts.addBranch(ts.AddLeaf(20, 5), ts.AddLeaf(10, 10)))
+		expected := []string{"leaf {index: 4 length: 6}", "leaf {index: 20 length: 5}", "leaf {index: 10 length: 2}"}
+		i := 0
+		for n := range ts.RangeIter(root, 4, 13) {
+			if n.String() != expected[i] {
+				t.Errorf("Expected %s, got %s", expected[i], n.String())
+			}
+			i++
+		}
+	})
+
+	t.Run("skips subtrees", func(t *testing.T) {
+		ts, idx := generateBalancedTree(10, 2)
+		visited := 0
+		ts.nodes = countingSlab{ts.nodes, &visited}
+		for range ts.RangeIter(idx, 2000, 10) {
+		}
+		if visited > 100 {
+			t.Error("Expected RangeIter to skip subtrees, but it read", visited, "nodes")
+		}
+	})
+}
+
+// countingSlab wraps a node slab, counting how many times nodes are read from it.
+type countingSlab struct {
+	Slab[node]
+	count *int
+}
+
+func (s countingSlab) Get(index uint32) node {
+	*s.count++
+	return s.Slab.Get(index)
+}
+
+func (s countingSlab) GetRef(index uint32) *node {
+	*s.count++
+	return s.Slab.GetRef(index)
+}
+
+func TestTreeSpanIter(t *testing.T) {
+	ts := NewTreeSlab()
+	root := ts.addBranch(ts.AddLeaf(0, 10), ts.addBranch(ts.AddLeaf(20, 5), ts.AddLeaf(10, 10)))
+	var spans [][2]uint32
+	for s, l := range ts.SpanIter(root) {
+		spans = append(spans, [2]uint32{s, l})
+	}
+	if len(spans) != 3 || spans[0] != [2]uint32{0, 10} || spans[1] != [2]uint32{20, 5} || spans[2] != [2]uint32{10, 10} {
+		t.Error("Expected [[0 10] [20 5] [10 10]], got", spans)
+	}
+
+	spans = spans[:0]
+	for s, l := range ts.RangeSpanIter(root, 8, 9) {
+		spans = append(spans, [2]uint32{s, l})
+	}
+	if len(spans) != 3 || spans[0] != [2]uint32{8, 2} || spans[1] != [2]uint32{20, 5} || spans[2] != [2]uint32{10, 2} {
+		t.Error("Expected [[8 2] [20 5] [10 2]], got", spans)
+	}
+}
+
+func TestWriteSpans(t *testing.T) {
+	ts := NewTreeSlab()
+	data := MinimalSlab[byte]{}
+	root := ts.AddLeaf(data.Add([]byte("hello world")...))
+	root = ts.insert(root, 5, ts.AddLeaf(data.Add([]byte(", cruel")...)))
+
+	for _, d := range []Slab[byte]{&data, NewCompressedSlab[byte]()} {
+		if d.Len() == 0 {
+			d.Add(data...)
+		}
+		var sb strings.Builder
+		n, err := WriteSpans(&sb, &ts, root, d, 3, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 10 || sb.String() != "lo, cruel " {
+			t.Errorf("Expected \"lo, cruel \", got %q", sb.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package tree
+
+// WalkOrder is the order in which Walk visits nodes.
+type WalkOrder int
+
+const (
+	// PreOrder visits each branch before its children.
+	PreOrder WalkOrder = iota
+	// PostOrder visits each branch after its children.
+	PostOrder
+)
+
+// WalkAction is returned by a Visitor to control how Walk carries on.
+type WalkAction int
+
+const (
+	// Continue carries on walking as normal.
+	Continue WalkAction = iota
+	// SkipChildren doesn't walk the children of the visited branch. It is equivalent to Continue in PostOrder walks,
+	// where the children have already been visited.
+	SkipChildren
+	// Stop ends the walk immediately.
+	Stop
+)
+
+// Visitor is called by Walk for each node it visits, with the index of the node, its depth below the root of the
+// walk, and the position of its first item within the walked tree.
+type Visitor func(index uint32, depth int, offset uint32, n *node) WalkAction
+
+// WalkOption configures a Walk.
+type WalkOption func(*walkOptions)
+
+type walkOptions struct {
+	order    WalkOrder
+	maxDepth int
+}
+
+// WithOrder sets the order in which Walk visits nodes. The default is PreOrder.
+func WithOrder(order WalkOrder) WalkOption {
+	return func(o *walkOptions) {
+		o.order = order
+	}
+}
+
+// WithMaxDepth stops Walk descending below the given depth, so nodes deeper than it aren't visited.
+func WithMaxDepth(depth int) WalkOption {
+	return func(o *walkOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// walkFrame is a node waiting to be visited, or in a PostOrder walk, to have its children pushed.
+type walkFrame struct {
+	index    uint32
+	depth    int
+	offset   uint32
+	expanded bool
+}
+
+// Walk walks the (sub)tree starting at a given node index, calling visit for each node, which controls whether the
+// walk carries on into the node's children, or stops entirely.
+// It uses an explicit stack rather than recursion, so even degenerate trees of any depth can be walked.
+// It returns false if the walk was stopped by the visitor.
+func (ts *TreeSlab) Walk(index uint32, visit Visitor, opts ...WalkOption) bool {
+	o := walkOptions{order: PreOrder, maxDepth: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	stack := []walkFrame{{index: index}}
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := ts.nodes.GetRef(f.index)
+		descend := !n.leaf && (o.maxDepth < 0 || f.depth < o.maxDepth)
+
+		if o.order == PostOrder && descend && !f.expanded {
+			f.expanded = true
+			stack = append(stack, f)
+			stack = ts.pushChildren(stack, f, n)
+			continue
+		}
+
+		switch visit(f.index, f.depth, f.offset, n) {
+		case Stop:
+			return false
+		case SkipChildren:
+			continue
+		}
+		if o.order == PreOrder && descend {
+			stack = ts.pushChildren(stack, f, n)
+		}
+	}
+	return true
+}
+
+// pushChildren pushes the children of a branch onto a Walk stack, right first so the left is visited first.
+func (ts *TreeSlab) pushChildren(stack []walkFrame, f walkFrame, n *node) []walkFrame {
+	return append(stack,
+		walkFrame{index: n.y, depth: f.depth + 1, offset: f.offset + ts.Len(n.x)},
+		walkFrame{index: n.x, depth: f.depth + 1, offset: f.offset},
+	)
+}
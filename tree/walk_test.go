@@ -0,0 +1,95 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	ts := NewTreeSlab()
+	root := ts.addBranch(
+		ts.AddLeaf(0, 10),
+		ts.addBranch(
+			ts.AddLeaf(10, 5),
+			ts.AddLeaf(15, 5),
+		),
+	)
+	trace := func(opts ...WalkOption) (string, bool) {
+		var sb strings.Builder
+		ok := ts.Walk(root, func(index uint32, depth int, offset uint32, n *node) WalkAction {
+			fmt.Fprintf(&sb, "%d:%d@%d ", index, depth, offset)
+			return Continue
+		}, opts...)
+		return strings.TrimSpace(sb.String()), ok
+	}
+
+	if s, ok := trace(); !ok || s != "4:0@0 0:1@0 3:1@10 1:2@10 2:2@15" {
+		t.Error("Unexpected pre-order walk:", s)
+	}
+	if s, _ := trace(WithOrder(PostOrder)); s != "0:1@0 1:2@10 2:2@15 3:1@10 4:0@0" {
+		t.Error("Unexpected post-order walk:", s)
+	}
+	if s, _ := trace(WithMaxDepth(1)); s != "4:0@0 0:1@0 3:1@10" {
+		t.Error("Unexpected depth limited walk:", s)
+	}
+	if s, _ := trace(WithOrder(PostOrder), WithMaxDepth(0)); s != "4:0@0" {
+		t.Error("Unexpected depth limited post-order walk:", s)
+	}
+
+	t.Run("skip children", func(t *testing.T) {
+		var visited []uint32
+		ts.Walk(root, func(index uint32, _ int, _ uint32, n *node) WalkAction {
+			visited = append(visited, index)
+			if index == 3 {
+				return SkipChildren
+			}
+			return Continue
+		})
+		if fmt.Sprint(visited) != "[4 0 3]" {
+			t.Error("Expected [4 0 3], got", visited)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		var visited []uint32
+		ok := ts.Walk(root, func(index uint32, _ int, _ uint32, n *node) WalkAction {
+			visited = append(visited, index)
+			if index == 0 {
+				return Stop
+			}
+			return Continue
+		})
+		if ok || fmt.Sprint(visited) != "[4 0]" {
+			t.Error("Expected a stopped walk of [4 0], got", visited)
+		}
+	})
+}
+
+func TestWalk_Degenerate(t *testing.T) {
+	// a left-skewed chain deep enough that recursion would be a real concern
+	const depth = 1 << 20
+	ts := NewTreeSlab()
+	root := ts.AddLeaf(0, 1)
+	for i := uint32(1); i < depth; i++ {
+		root = ts.addBranch(root, ts.AddLeaf(i, 1))
+	}
+	if ts.Len(root) != depth {
+		t.Error("Expected", depth, "got", ts.Len(root))
+	}
+	deepest := 0
+	ts.Walk(root, func(_ uint32, d int, _ uint32, _ *node) WalkAction {
+		deepest = max(deepest, d)
+		return Continue
+	})
+	if deepest != depth-1 {
+		t.Error("Expected a depth of", depth-1, "got", deepest)
+	}
+	i := uint32(0)
+	for n := range ts.IndexIter(root) {
+		if n != i {
+			t.Fatal("Expected", i, "got", n)
+		}
+		i++
+	}
+}